@@ -15,17 +15,36 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/golangee/reflectplus"
 	"github.com/golangee/reflectplus/golang"
+	"github.com/golangee/reflectplus/meta"
 	"log"
+	"log/slog"
+	"os"
 	"strings"
 )
 
 func main() {
 	dir := flag.String("dir", "", "the directory to scan")
 	patterns := flag.String("patterns", "", "the path patterns to parse, e.g. github.com/myproject/mypath/...;github.com/other/path/...")
+	canonical := flag.Bool("canonical", false, "uses readable Go-like type notations as declaration ids instead of hashes.")
+	tags := flag.String("tags", "", "comma separated build tags to satisfy, e.g. integration,debug")
+	goos := flag.String("goos", "", "the target operating system, e.g. linux. Defaults to the environment.")
+	goarch := flag.String("goarch", "", "the target architecture, e.g. arm64. Defaults to the environment.")
+	env := flag.String("env", "", "additional environment variables for the build system, e.g. CGO_ENABLED=0;GOFLAGS=-mod=vendor")
+	buildFlags := flag.String("buildflags", "", "additional flags for the build system, e.g. -mod=vendor;-trimpath")
+	tests := flag.Bool("tests", false, "includes _test.go files and external test packages.")
+	keepBodies := flag.Bool("keepbodies", false, "keeps function bodies after parsing, which is slower and requires more memory.")
+	depSyntax := flag.Bool("depsyntax", false, "parses all dependencies from source to keep their docs and annotations, which is much slower.")
+	workers := flag.Int("workers", 0, "the amount of packages to process concurrently. Defaults to the amount of CPUs.")
+	platforms := flag.String("platforms", "", "parses and merges multiple build configurations, e.g. linux/amd64;windows/arm64/integration,debug")
+	cache := flag.Bool("cache", false, "reuses the parsed packages of previous runs from the user cache dir, if they are unchanged.")
+	scope := flag.Int("scope", -1, "keeps only the dependency types, which are referenced within the given depth. Defaults to all referenced types.")
+	timeout := flag.Duration("timeout", 0, "aborts the parsing after the given duration, e.g. 30s. Defaults to no timeout.")
+	verbose := flag.Bool("v", false, "logs the progress and the debug output of the build system to stderr.")
 	help := flag.Bool("help", false, "shows this help.")
 	flag.Parse()
 
@@ -38,18 +57,82 @@ func main() {
 	var prj *golang.Project
 	var err error
 
+	opts := golang.Options{
+		CanonicalIds:     *canonical,
+		Tags:             splitFlag(*tags, ","),
+		GOOS:             *goos,
+		GOARCH:           *goarch,
+		Env:              splitFlag(*env, ";"),
+		BuildFlags:       splitFlag(*buildFlags, ";"),
+		Tests:            *tests,
+		KeepFuncBodies:   *keepBodies,
+		DependencySyntax: *depSyntax,
+		Workers:          *workers,
+		Scoped:           *scope >= 0,
+		ScopeDepth:       *scope,
+	}
+
+	for _, platform := range splitFlag(*platforms, ";") {
+		segments := strings.SplitN(platform, "/", 3)
+		if len(segments) < 2 {
+			log.Fatalf("invalid platform '%s', expected goos/goarch[/tags]", platform)
+		}
+
+		p := meta.Platform{GOOS: segments[0], GOARCH: segments[1]}
+		if len(segments) == 3 {
+			p.Tags = splitFlag(segments[2], ",")
+		}
+
+		opts.Platforms = append(opts.Platforms, p)
+	}
+
+	if *verbose {
+		opts.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		opts.Progress = func(p golang.Progress) {
+			opts.Logger.Info("progress", "phase", p.Phase, "package", p.Package, "done", p.Done, "total", p.Total,
+				"cached", p.Cached)
+		}
+	}
+
+	if *cache {
+		opts.CacheDir, err = golang.DefaultCacheDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
 	if *dir == "" && *patterns == "" {
-		prj, err = reflectplus.ParseModule()
+		prj, err = reflectplus.ParseModuleContext(ctx, opts)
 	} else {
-		prj, err = reflectplus.Parse(golang.Options{
-			Dir:      *dir,
-			Patterns: strings.Split(*patterns, ";"),
-		})
+		opts.Dir = *dir
+		opts.Patterns = strings.Split(*patterns, ";")
+		prj, err = reflectplus.ParseContext(ctx, opts)
 	}
 
-	if err != nil{
+	if err != nil {
 		log.Fatal(err)
 	}
 
 	fmt.Println(prj.String())
+
+	// the declarations of the other packages are still usable, even if the result is incomplete
+	for _, d := range prj.Diagnostics() {
+		fmt.Fprintln(os.Stderr, d)
+	}
+}
+
+// splitFlag returns nil for an empty flag value, which is not the same as strings.Split.
+func splitFlag(value, sep string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, sep)
 }
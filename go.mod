@@ -1,8 +1,13 @@
 module github.com/golangee/reflectplus
 
-go 1.14
+go 1.25.0
 
 require (
 	github.com/golangee/src v0.0.0-20200828070225-f6a86101e3ba
-	golang.org/x/tools v0.0.0-20200713235242-6acd2ab80ede
+	golang.org/x/tools v0.44.0
+)
+
+require (
+	golang.org/x/mod v0.35.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
 )
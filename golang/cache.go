@@ -0,0 +1,181 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/golangee/reflectplus/meta"
+	"golang.org/x/tools/go/packages"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// cacheVersion invalidates all cached tables, whenever the meta model or the parser changes incompatibly.
+const cacheVersion = 6
+
+// DefaultCacheDir returns the reflectplus directory within the user cache dir, see also os.UserCacheDir.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "reflectplus"), nil
+}
+
+// A tableCache persists the tables of single packages as json files. The key of a package covers the contents
+// of its files, the keys of its imports and the build configuration, so that a cached table is never outdated.
+type tableCache struct {
+	dir     string
+	config  []byte
+	overlay map[string][]byte
+	keys    map[string]string
+}
+
+func newTableCache(opts Options) (*tableCache, error) {
+	// everything which changes the outcome of the same files, including the environment of the build system
+	config, err := json.Marshal(struct {
+		Version                            int
+		GoVersion                          string
+		Tags, Env, BuildFlags              []string
+		GOOS, GOARCH, GOFLAGS, CGO_ENABLED string
+		Tests, Canonical, Bodies, DepSrc   bool
+	}{
+		Version:     cacheVersion,
+		GoVersion:   runtime.Version(),
+		Tags:        opts.Tags,
+		Env:         opts.Env,
+		BuildFlags:  opts.BuildFlags,
+		GOOS:        opts.GOOS + "/" + os.Getenv("GOOS"),
+		GOARCH:      opts.GOARCH + "/" + os.Getenv("GOARCH"),
+		GOFLAGS:     os.Getenv("GOFLAGS"),
+		CGO_ENABLED: os.Getenv("CGO_ENABLED"),
+		Tests:       opts.Tests,
+		Canonical:   opts.CanonicalIds,
+		Bodies:      opts.KeepFuncBodies,
+		DepSrc:      opts.DependencySyntax,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &tableCache{
+		dir:     opts.CacheDir,
+		config:  config,
+		overlay: opts.Overlay,
+		keys:    map[string]string{},
+	}, nil
+}
+
+// key calculates the key of the package, which depends on the keys of all of its imports.
+func (c *tableCache) key(pkg *packages.Package) (string, error) {
+	if key, ok := c.keys[pkg.ID]; ok {
+		return key, nil
+	}
+
+	hasher := sha256.New()
+	hasher.Write(c.config)
+	hasher.Write([]byte("\x00" + pkg.ID))
+
+	for _, file := range pkg.GoFiles {
+		content, ok := c.overlay[file]
+		if !ok {
+			var err error
+			content, err = os.ReadFile(file)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		contentHash := sha256.Sum256(content)
+		hasher.Write([]byte("\x00" + file + "\x00"))
+		hasher.Write(contentHash[:])
+	}
+
+	importPaths := make([]string, 0, len(pkg.Imports))
+	for importPath := range pkg.Imports {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+
+	for _, importPath := range importPaths {
+		importKey, err := c.key(pkg.Imports[importPath])
+		if err != nil {
+			return "", err
+		}
+
+		hasher.Write([]byte("\x00" + importPath + "\x00" + importKey))
+	}
+
+	key := hex.EncodeToString(hasher.Sum(nil))
+	c.keys[pkg.ID] = key
+
+	return key, nil
+}
+
+// file returns the name of the cache file of the key.
+func (c *tableCache) file(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// load returns the cached table or false, if there is no valid entry.
+func (c *tableCache) load(key string) (*meta.Table, bool) {
+	buf, err := os.ReadFile(c.file(key))
+	if err != nil {
+		return nil, false
+	}
+
+	table := meta.NewTable()
+	if err := json.Unmarshal(buf, table); err != nil {
+		return nil, false
+	}
+
+	return table, true
+}
+
+// store writes the table atomically, so that concurrent runs never see a partial file.
+func (c *tableCache) store(key string, table *meta.Table) error {
+	buf, err := json.Marshal(table)
+	if err != nil {
+		return err
+	}
+
+	name := c.file(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(buf); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
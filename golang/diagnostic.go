@@ -0,0 +1,244 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"github.com/golangee/reflectplus/meta"
+	"go/ast"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"sort"
+	"strings"
+)
+
+// Severity tells, whether a Diagnostic makes the result incomplete.
+type Severity int
+
+const (
+	// SeverityError means that declarations are missing or incomplete.
+	SeverityError Severity = iota
+
+	// SeverityWarning means that the declarations are complete, e.g. for an unused variable.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// DiagnosticKind tells, which step has reported a Diagnostic.
+type DiagnosticKind string
+
+const (
+	// DiagnosticLoad is reported by the build system, e.g. for a missing module.
+	DiagnosticLoad DiagnosticKind = "load"
+
+	// DiagnosticParse is a syntax error.
+	DiagnosticParse DiagnosticKind = "parse"
+
+	// DiagnosticType is reported by the type checker.
+	DiagnosticType DiagnosticKind = "type"
+
+	// DiagnosticAnnotation is a malformed annotation. The declaration is kept without annotations.
+	DiagnosticAnnotation DiagnosticKind = "annotation"
+
+	// DiagnosticUnsupported means that a declaration cannot be represented by the table. The declaration and the
+	// declarations, which depend on it, are omitted, but the rest of the package is kept.
+	DiagnosticUnsupported DiagnosticKind = "unsupported"
+)
+
+// A Diagnostic describes a problem, which has been found while parsing. Declarations which are affected by an
+// error may be missing, however a broken package never blocks the other packages.
+type Diagnostic struct {
+	// Location is file:line:col or empty, if the problem has no position, e.g. a missing package.
+	Location meta.Location
+
+	Severity Severity
+	Kind     DiagnosticKind
+
+	// Package is the id of the affected package.
+	Package string
+
+	// DeclId is the id of the affected package level declaration or method or empty, if unknown. The declaration
+	// itself may be missing, e.g. if it is unexported or broken.
+	DeclId meta.DeclId
+
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	sb := &strings.Builder{}
+	if d.Location != "" {
+		sb.WriteString(string(d.Location))
+		sb.WriteString(": ")
+	}
+
+	sb.WriteString(d.Severity.String())
+	sb.WriteString(": ")
+	sb.WriteString(d.Message)
+
+	return sb.String()
+}
+
+// sortDiagnostics orders the diagnostics by their location and removes duplicates, e.g. from multiple platforms.
+func sortDiagnostics(list []Diagnostic) []Diagnostic {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Location != list[j].Location {
+			return list[i].Location.Less(list[j].Location)
+		}
+
+		return list[i].Message < list[j].Message
+	})
+
+	res := list[:0]
+	for i, d := range list {
+		if i == 0 || d != list[i-1] {
+			res = append(res, d)
+		}
+	}
+
+	return res
+}
+
+// packageDiagnostics converts the errors of the build system and the type checker.
+func packageDiagnostics(ctx *parseCtx, pkg *packages.Package) []Diagnostic {
+	var res []Diagnostic
+	for _, err := range pkg.Errors {
+		// the build system compiles the package for its export data, which repeats the errors of the type checker
+		if err.Kind == packages.ListError && strings.HasPrefix(err.Msg, "# ") {
+			continue
+		}
+
+		d := Diagnostic{
+			Location: meta.Location(err.Pos),
+			Severity: SeverityError,
+			Kind:     DiagnosticLoad,
+			Package:  pkg.ID,
+			Message:  err.Msg,
+		}
+
+		switch err.Kind {
+		case packages.TypeError:
+			// taken from the TypeErrors, which are more detailed
+			continue
+		case packages.ParseError:
+			d.Kind = DiagnosticParse
+		}
+
+		res = append(res, d)
+	}
+
+	for _, err := range pkg.TypeErrors {
+		pos := ctx.fset.Position(err.Pos)
+		d := Diagnostic{
+			Severity: SeverityError,
+			Kind:     DiagnosticType,
+			Package:  pkg.ID,
+			Message:  err.Msg,
+		}
+
+		if pos.IsValid() {
+			d.Location = meta.NewLocation(pos.Filename, pos.Line, pos.Column)
+		}
+
+		if err.Soft {
+			d.Severity = SeverityWarning
+		}
+
+		if ident := enclosingDeclIdent(pkg.Syntax, err); ident != nil && pkg.TypesInfo.Defs[ident] != nil {
+			d.DeclId = ctx.objDeclId(pkg.TypesInfo.Defs[ident])
+		}
+
+		res = append(res, d)
+	}
+
+	return res
+}
+
+// objDeclId returns the id of a package level object or method, like putFunc or putType would do.
+func (c *parseCtx) objDeclId(obj types.Object) meta.DeclId {
+	if fun, ok := obj.(*types.Func); ok {
+		if recv := fun.Type().(*types.Signature).Recv(); recv != nil {
+			recvType := recv.Type()
+			if ptr, ok := recvType.(*types.Pointer); ok {
+				recvType = ptr.Elem()
+			}
+
+			if named, ok := recvType.(*types.Named); ok {
+				recvObj := named.Origin().Obj()
+				return c.declId(string(c.pkgDeclId(recvObj.Pkg(), recvObj.Name())) + "." + fun.Name())
+			}
+
+			return ""
+		}
+	}
+
+	if obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+		return ""
+	}
+
+	return c.pkgDeclId(obj.Pkg(), obj.Name())
+}
+
+// enclosingDeclIdent returns the declaring identifier of the package level declaration, which contains the
+// position of the error, or nil.
+func enclosingDeclIdent(files []*ast.File, err types.Error) *ast.Ident {
+	for _, file := range files {
+		if err.Pos < file.Pos() || err.Pos > file.End() {
+			continue
+		}
+
+		for _, decl := range file.Decls {
+			if err.Pos < decl.Pos() || err.Pos > decl.End() {
+				continue
+			}
+
+			switch t := decl.(type) {
+			case *ast.FuncDecl:
+				return t.Name
+			case *ast.GenDecl:
+				for _, spec := range t.Specs {
+					if err.Pos < spec.Pos() || err.Pos > spec.End() {
+						continue
+					}
+
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						return s.Name
+					case *ast.ValueSpec:
+						// the last name before the error, e.g. B for an error in var A, B = 1, undefined
+						name := s.Names[0]
+						for _, n := range s.Names {
+							if n.Pos() <= err.Pos {
+								name = n
+							}
+						}
+
+						return name
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"github.com/golangee/reflectplus/meta"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// putImplementations relates each concrete named type with the named interfaces of the table, which are
+// implemented by the type or only by its pointer. Generic declarations and interfaces without methods are
+// not considered, because they are either not comparable or implemented by anything. Only the table is
+// inspected, so that this also works for tables from the cache, which have no type information.
+func putImplementations(table *meta.Table) {
+	identities := newTypeIdentities(table)
+
+	var ifaces, concretes []meta.DeclId
+	for _, id := range table.DeclIds() {
+		named := table.Declarations[id].Named
+		if named == nil || named.Func || len(named.TypeParams) > 0 {
+			continue
+		}
+
+		if iface := table.Declarations[named.Underlying].Interface; iface != nil {
+			if len(iface.AllMethods) > 0 && isMethodSet(table, named.Underlying) {
+				ifaces = append(ifaces, id)
+			}
+		} else {
+			concretes = append(concretes, id)
+		}
+	}
+
+	for _, id := range concretes {
+		named := table.Declarations[id].Named
+		valueSet := identities.methodSet(named.ValueMethodSet)
+		pointerSet := identities.methodSet(named.PointerMethodSet)
+
+		named.Implements = nil
+		for _, ifaceId := range ifaces {
+			methods := table.Declarations[table.Declarations[ifaceId].Named.Underlying].Interface.AllMethods
+			switch {
+			case identities.containsAll(valueSet, methods):
+				named.Implements = append(named.Implements, meta.Implementation{DeclId: ifaceId})
+			case identities.containsAll(pointerSet, methods):
+				named.Implements = append(named.Implements, meta.Implementation{DeclId: ifaceId, Pointer: true})
+			}
+		}
+	}
+}
+
+// isMethodSet returns true, if the interface has no type set constraints, like unions.
+func isMethodSet(table *meta.Table, ifaceId meta.DeclId) bool {
+	for _, id := range table.Declarations[ifaceId].Interface.Embeddeds {
+		id = embeddedInterface(table, id)
+		if table.Declarations[id].Interface == nil || !isMethodSet(table, id) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// embeddedInterface resolves aliases, instances and named types of an embedded element down to its interface, e.g.
+// Repository[MyStruct] to the interface of Repository. A union or any other type set term is returned as is.
+func embeddedInterface(table *meta.Table, id meta.DeclId) meta.DeclId {
+	for {
+		decl := table.Declarations[id]
+		switch {
+		case decl.Alias != nil:
+			id = decl.Alias.Target
+		case decl.Instance != nil:
+			id = decl.Instance.Origin
+		case decl.Named != nil && decl.Named.Underlying != "":
+			id = decl.Named.Underlying
+		default:
+			return id
+		}
+	}
+}
+
+// typeIdentities calculates keys, which are equal for identical types. DeclIds are not sufficient, because aliases
+// have their own declaration and the signatures of methods include their receiver.
+type typeIdentities struct {
+	table       *meta.Table
+	importTable map[meta.DeclId]meta.PkgId
+	cache       map[meta.DeclId]string
+}
+
+func newTypeIdentities(table *meta.Table) *typeIdentities {
+	return &typeIdentities{
+		table:       table,
+		importTable: table.CreateImportTable(),
+		cache:       map[meta.DeclId]string{},
+	}
+}
+
+// methodSet returns the set of method keys.
+func (t *typeIdentities) methodSet(methods []meta.DeclId) map[string]bool {
+	res := map[string]bool{}
+	for _, id := range methods {
+		res[t.method(id)] = true
+	}
+
+	return res
+}
+
+// containsAll returns true, if the set contains all given methods.
+func (t *typeIdentities) containsAll(set map[string]bool, methods []meta.DeclId) bool {
+	for _, id := range methods {
+		if !set[t.method(id)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// method returns the key of a named method, which consists of its name and its signature without the receiver.
+// Unexported methods of different packages are never identical.
+func (t *typeIdentities) method(id meta.DeclId) string {
+	named := t.table.Declarations[id].Named
+	key := named.Name + t.of(named.Underlying)
+	if !token.IsExported(named.Name) {
+		key = string(t.importTable[id]) + "." + key
+	}
+
+	return key
+}
+
+// of returns the identity key of the type.
+func (t *typeIdentities) of(id meta.DeclId) string {
+	if key, ok := t.cache[id]; ok {
+		return key
+	}
+
+	key := t.compute(id)
+	t.cache[id] = key
+
+	return key
+}
+
+func (t *typeIdentities) compute(id meta.DeclId) string {
+	decl := t.table.Declarations[id]
+	switch {
+	case decl.Alias != nil && len(decl.Alias.TypeParams) == 0:
+		return t.of(decl.Alias.Target)
+	case decl.Basic != nil:
+		return string(decl.Basic.Kind)
+	case decl.Slice != nil:
+		return "[]" + t.of(decl.Slice.DeclId)
+	case decl.Array != nil:
+		return "[" + strconv.FormatInt(decl.Array.Len, 10) + "]" + t.of(decl.Array.DeclId)
+	case decl.Pointer != nil:
+		return "*" + t.of(decl.Pointer.Base)
+	case decl.Map != nil:
+		return "map[" + t.of(decl.Map.Key) + "]" + t.of(decl.Map.Value)
+	case decl.Channel != nil:
+		return "chan(" + string(decl.Channel.ChanDir) + ") " + t.of(decl.Channel.DeclId)
+	case decl.Signature != nil:
+		return t.signature(decl.Signature)
+	case decl.Struct != nil:
+		var fields []string
+		for _, f := range decl.Struct.Fields {
+			fields = append(fields, f.Name+" "+strconv.FormatBool(f.Embedded)+" "+t.of(f.DeclId)+" "+f.Tag)
+		}
+
+		return "struct{" + strings.Join(fields, "; ") + "}"
+	case decl.Interface != nil:
+		var elems []string
+		for _, m := range decl.Interface.AllMethods {
+			elems = append(elems, t.method(m))
+		}
+
+		for _, e := range decl.Interface.Embeddeds {
+			// the methods of embedded interfaces are already contained
+			if t.table.Declarations[e].Union != nil {
+				elems = append(elems, t.of(e))
+			}
+		}
+		sort.Strings(elems)
+
+		return "interface{" + strings.Join(elems, "; ") + "}"
+	case decl.Union != nil:
+		var terms []string
+		for _, term := range decl.Union.Terms {
+			if term.Tilde {
+				terms = append(terms, "~"+t.of(term.DeclId))
+			} else {
+				terms = append(terms, t.of(term.DeclId))
+			}
+		}
+		sort.Strings(terms)
+
+		return strings.Join(terms, " | ")
+	case decl.Instance != nil:
+		var args []string
+		for _, arg := range decl.Instance.TypeArgs {
+			args = append(args, t.of(arg))
+		}
+
+		return t.of(decl.Instance.Origin) + "[" + strings.Join(args, ", ") + "]"
+	default:
+		// named types, type parameters and generic aliases are only identical to themselves
+		return string(id)
+	}
+}
+
+// signature returns the key of the signature without its receiver and parameter names.
+func (t *typeIdentities) signature(sig *meta.Signature) string {
+	var params, results []string
+	for _, p := range sig.Params {
+		params = append(params, t.of(p.DeclId))
+	}
+
+	for _, p := range sig.Results {
+		results = append(results, t.of(p.DeclId))
+	}
+
+	variadic := ""
+	if sig.Variadic {
+		variadic = "..."
+	}
+
+	return "func(" + strings.Join(params, ", ") + variadic + ") (" + strings.Join(results, ", ") + ")"
+}
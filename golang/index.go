@@ -0,0 +1,232 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// knownOS and knownArch are the GOOS and GOARCH values, which go/build recognizes in file names.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+		"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true, "openbsd": true,
+		"plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+	}
+
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true, "arm64be": true,
+		"loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+		"mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true, "riscv": true, "riscv64": true,
+		"s390": true, "s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// An indexEntry describes the declaration of an identifier.
+type indexEntry struct {
+	// node is the declaring *ast.TypeSpec, *ast.ValueSpec, *ast.FuncDecl or the *ast.Field of an interface method.
+	node ast.Node
+
+	// decl is the enclosing *ast.GenDecl or *ast.FuncDecl, which may be the node itself.
+	decl ast.Decl
+}
+
+// A posIndex looks up declarations by the position of their declaring identifier, which is the position of
+// the according types.Object. It is built once per file, so that each lookup is done in constant time.
+type posIndex struct {
+	fset        *token.FileSet
+	decls       map[token.Pos]indexEntry
+	constraints map[*token.File]string
+}
+
+func newPosIndex(fset *token.FileSet) *posIndex {
+	return &posIndex{
+		fset:        fset,
+		decls:       map[token.Pos]indexEntry{},
+		constraints: map[*token.File]string{},
+	}
+}
+
+// indexFile creates the index of a single file, so that files can be indexed concurrently and merged afterwards.
+func indexFile(fset *token.FileSet, file *ast.File) *posIndex {
+	idx := newPosIndex(fset)
+
+	tokFile := fset.File(file.Pos())
+	if expr := parseBuildConstraint(tokFile.Name(), file); expr != "" {
+		idx.constraints[tokFile] = expr
+	}
+
+	for _, decl := range file.Decls {
+		ast.PreorderStack(decl, nil, func(node ast.Node, stack []ast.Node) bool {
+			// the nearest enclosing declaration, which differs from the top level one for local declarations
+			enclosing := decl
+			for i := len(stack) - 1; i >= 0; i-- {
+				if d, ok := stack[i].(ast.Decl); ok {
+					enclosing = d
+					break
+				}
+			}
+
+			switch t := node.(type) {
+			case *ast.FuncDecl:
+				idx.decls[t.Name.Pos()] = indexEntry{node: t, decl: t}
+			case *ast.TypeSpec:
+				idx.decls[t.Name.Pos()] = indexEntry{node: t, decl: enclosing}
+			case *ast.ValueSpec:
+				for _, name := range t.Names {
+					idx.decls[name.Pos()] = indexEntry{node: t, decl: enclosing}
+				}
+			case *ast.InterfaceType:
+				for _, method := range t.Methods.List {
+					for _, name := range method.Names {
+						idx.decls[name.Pos()] = indexEntry{node: method, decl: enclosing}
+					}
+				}
+			}
+
+			return true
+		})
+	}
+
+	return idx
+}
+
+// parseBuildConstraint returns the normalized build constraint of the file or the empty string. It combines the
+// implicit constraint of the file name, like windows for x_windows.go, with the //go:build expression.
+func parseBuildConstraint(filename string, file *ast.File) string {
+	expr := fileNameConstraint(filename)
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+
+		for _, c := range group.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+
+			if goBuild, err := constraint.Parse(c.Text); err == nil {
+				if expr == nil {
+					expr = goBuild
+				} else {
+					expr = &constraint.AndExpr{X: expr, Y: goBuild}
+				}
+			}
+		}
+	}
+
+	if expr == nil {
+		return ""
+	}
+
+	return expr.String()
+}
+
+// fileNameConstraint returns the GOOS and GOARCH terms, which are implied by the name of the file following the
+// rules of go/build, like linux && arm64 for x_linux_arm64.go, or nil.
+func fileNameConstraint(filename string) constraint.Expr {
+	name := filepath.Base(filename)
+	if dot := strings.Index(name, "."); dot >= 0 {
+		name = name[:dot]
+	}
+
+	// the part before the first underscore is never a term, so that e.g. windows.go has no constraint
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return nil
+	}
+
+	parts := strings.Split(name[i:], "_")
+	if n := len(parts); n >= 2 && parts[n-1] == "test" {
+		parts = parts[:n-1]
+	}
+
+	n := len(parts)
+	switch {
+	case n >= 2 && knownOS[parts[n-2]] && knownArch[parts[n-1]]:
+		return &constraint.AndExpr{X: &constraint.TagExpr{Tag: parts[n-2]}, Y: &constraint.TagExpr{Tag: parts[n-1]}}
+	case n >= 1 && (knownOS[parts[n-1]] || knownArch[parts[n-1]]):
+		return &constraint.TagExpr{Tag: parts[n-1]}
+	default:
+		return nil
+	}
+}
+
+// merge adds all entries of the other index.
+func (x *posIndex) merge(other *posIndex) {
+	for pos, entry := range other.decls {
+		x.decls[pos] = entry
+	}
+
+	for file, expr := range other.constraints {
+		x.constraints[file] = expr
+	}
+}
+
+// doc returns the trimmed doc comment of the declaration. The doc of a type declaration consists of the
+// comment of the type block and of the spec itself. See also
+// https://github.com/golang/go/issues/27477#issuecomment-418563062 for details.
+func (x *posIndex) doc(pos token.Pos) string {
+	entry, ok := x.decls[pos]
+	if !ok {
+		return ""
+	}
+
+	s := ""
+	switch t := entry.node.(type) {
+	case *ast.TypeSpec:
+		if genDecl, ok := entry.decl.(*ast.GenDecl); ok {
+			s += genDecl.Doc.Text()
+		}
+		s += t.Doc.Text()
+	case *ast.FuncDecl:
+		s += t.Doc.Text()
+	case *ast.Field:
+		s += t.Doc.Text()
+	}
+
+	return strings.TrimSpace(s)
+}
+
+// typeSpec returns the declaring type spec or nil.
+func (x *posIndex) typeSpec(pos token.Pos) *ast.TypeSpec {
+	spec, _ := x.decls[pos].node.(*ast.TypeSpec)
+	return spec
+}
+
+// valueSpec returns the declaring value spec and its declaration or nil.
+func (x *posIndex) valueSpec(pos token.Pos) (*ast.GenDecl, *ast.ValueSpec) {
+	entry := x.decls[pos]
+	spec, ok := entry.node.(*ast.ValueSpec)
+	if !ok {
+		return nil, nil
+	}
+
+	genDecl, _ := entry.decl.(*ast.GenDecl)
+	return genDecl, spec
+}
+
+// buildConstraint returns the build constraint of the file, which contains the position, or the empty string.
+func (x *posIndex) buildConstraint(pos token.Pos) string {
+	if !pos.IsValid() {
+		return ""
+	}
+
+	return x.constraints[x.fset.File(pos)]
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestPosIndex(t *testing.T) {
+	const code = `//go:build linux || darwin
+
+package stuff
+
+// Types doc
+type (
+	// A doc
+	A interface {
+		// Method doc
+		Method()
+	}
+)
+
+// B doc
+func B() {
+	// Local doc
+	type Local int
+}
+
+// Values doc
+var (
+	// X doc
+	X, Y = 1, 2
+)
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "stuff.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := newPosIndex(fset)
+	idx.merge(indexFile(fset, file))
+
+	pos := func(ident string) token.Pos {
+		return file.FileStart + token.Pos(strings.Index(code, ident))
+	}
+
+	docs := map[string]string{
+		"A interface":   "Types doc\nA doc",
+		"Method()":      "Method doc",
+		"B()":           "B doc",
+		"Local int":     "Local doc",
+		"X, Y":          "",
+		"Unknown":       "",
+		"package stuff": "",
+	}
+
+	for ident, doc := range docs {
+		if got := idx.doc(pos(ident)); got != doc {
+			t.Fatalf("%s: expected '%s' but got '%s'", ident, doc, got)
+		}
+	}
+
+	if spec := idx.typeSpec(pos("Local int")); spec == nil || spec.Name.Name != "Local" {
+		t.Fatalf("unexpected local type spec %v", spec)
+	}
+
+	genDecl, spec := idx.valueSpec(pos("Y = 1"))
+	if genDecl == nil || genDecl.Doc.Text() != "Values doc\n" || spec.Names[1].Name != "Y" {
+		t.Fatal("unexpected value spec")
+	}
+
+	if genDecl, _ := idx.valueSpec(pos("B()")); genDecl != nil {
+		t.Fatal("a function is not a value spec")
+	}
+
+	if expr := idx.buildConstraint(file.Decls[0].(*ast.GenDecl).Pos()); expr != "linux || darwin" {
+		t.Fatalf("unexpected build constraint '%s'", expr)
+	}
+}
+
+func TestParseBuildConstraint(t *testing.T) {
+	const tagged = "//go:build integration || !cgo\n\npackage stuff\n"
+
+	for _, r := range []struct {
+		Name, Code, Expected string
+	}{
+		{"stuff.go", "package stuff\n", ""},
+		{"windows.go", "package stuff\n", ""},
+		{"stuff_windows.go", "package stuff\n", "windows"},
+		{"stuff_linux_arm64.go", "package stuff\n", "linux && arm64"},
+		{"stuff_amd64.go", "package stuff\n", "amd64"},
+		{"stuff_windows_test.go", "package stuff\n", "windows"},
+		{"x/stuff_linux_amd64.go", "package stuff\n", "linux && amd64"},
+		{"stuff_integration.go", "package stuff\n", ""},
+		{"stuff_linux_foo.go", "package stuff\n", ""},
+		{"stuff.go", tagged, "integration || !cgo"},
+		{"stuff_linux_arm64.go", tagged, "linux && arm64 && (integration || !cgo)"},
+	} {
+		file, err := parser.ParseFile(token.NewFileSet(), r.Name, r.Code, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := parseBuildConstraint(r.Name, file); got != r.Expected {
+			t.Fatalf("%s: expected '%s' but got '%s'", r.Name, r.Expected, got)
+		}
+	}
+}
@@ -14,6 +14,15 @@
 
 package golang
 
+import (
+	"github.com/golangee/reflectplus/meta"
+	"golang.org/x/tools/go/packages"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+)
+
 // Options for the reflectplus parser
 type Options struct {
 	// Dir is the directory in which to run the build system's query tool that provides information about the packages.
@@ -22,4 +31,131 @@ type Options struct {
 
 	// Patterns contains the root packages to parse, e.g. github.com/golangee/...
 	Patterns []string
+
+	// CanonicalIds uses the unambiguous Go-like notation of a declaration as its DeclId, instead of a hash of it,
+	// e.g. map[string]*github.com/myproject/stuff.MyStruct. Both kinds of ids are stable across runs and machines,
+	// but not across versions of this parser: whenever a notation changes, the hashed ids change as well.
+	CanonicalIds bool
+
+	// Tags are the build tags to satisfy, e.g. integration for files guarded by //go:build integration.
+	Tags []string
+
+	// GOOS is the target operating system, e.g. linux. If empty, the value of the environment is used.
+	GOOS string
+
+	// GOARCH is the target architecture, e.g. arm64. If empty, the value of the environment is used.
+	GOARCH string
+
+	// Env contains additional environment variables for the build system, like CGO_ENABLED=0. They
+	// are appended to the current environment, so that they take precedence.
+	Env []string
+
+	// BuildFlags are passed to the build system's query tool, e.g. -mod=vendor.
+	BuildFlags []string
+
+	// Tests also includes the _test.go files and the external test packages of the matched packages.
+	Tests bool
+
+	// Platforms parses the patterns once for each build configuration and merges the results. Each
+	// declaration records the platforms in which it exists. The Tags of a platform are added to the
+	// common Tags, GOOS and GOARCH are replaced.
+	Platforms []meta.Platform
+
+	// Overlay maps absolute file names to their contents, which replace the files on disk or add new ones,
+	// e.g. the unsaved buffers of an editor.
+	Overlay map[string][]byte
+
+	// KeepFuncBodies disables the removal of function and method bodies after parsing. The bodies are never
+	// inspected, so removing them saves a lot of time and memory, especially for the type checking.
+	KeepFuncBodies bool
+
+	// DependencySyntax parses and type checks all dependencies from source, including the standard library.
+	// Otherwise, only the packages of the Patterns are parsed and the dependencies are loaded from the compiler
+	// export data, which is much faster, but dependency declarations have no doc comments and annotations.
+	// In both cases, only declarations of dependencies which are referenced are put into the table.
+	DependencySyntax bool
+
+	// Workers is the amount of packages, whose declarations are processed concurrently. If zero, all
+	// available CPUs are used. The result is always the same, regardless of the amount of workers.
+	Workers int
+
+	// CacheDir enables the persistent cache of the tables of the parsed packages, see also DefaultCacheDir. A
+	// package is only parsed again, if its files, its dependencies or the build configuration have changed.
+	CacheDir string
+
+	// Scoped keeps all declarations of the packages of the Patterns, but only those declarations of dependencies,
+	// which are referenced by them within the ScopeDepth. Otherwise, all transitively referenced declarations of
+	// dependencies are kept, which usually includes large parts of the standard library.
+	Scoped bool
+
+	// ScopeDepth is the amount of named types of dependencies, which may be passed through from a declaration
+	// of the Patterns, if Scoped is set. Named types of dependencies just beyond the depth are kept as opaque
+	// declarations without underlying type and methods. So zero keeps only the names of the directly referenced
+	// dependency types.
+	ScopeDepth int
+
+	// Logger receives the debug output of the parser and of the build system. If nil, nothing is logged.
+	Logger *slog.Logger
+
+	// Progress is called each time a package has completed a phase. The calls are never concurrent. If nil,
+	// no progress is reported. Only PhaseTable is reported per package. PhaseLoad and PhaseTypeCheck are
+	// aggregate-only: the build system loads and type checks all packages in a single step without per-package
+	// callbacks, so each of them is reported once with an empty Package and the amount of packages as Done and Total.
+	Progress func(Progress)
+}
+
+// environ returns the environment for the build system or nil to inherit the current one.
+func (o Options) environ() []string {
+	if len(o.Env) == 0 && o.GOOS == "" && o.GOARCH == "" {
+		return nil
+	}
+
+	env := append(os.Environ(), o.Env...)
+	if o.GOOS != "" {
+		env = append(env, "GOOS="+o.GOOS)
+	}
+
+	if o.GOARCH != "" {
+		env = append(env, "GOARCH="+o.GOARCH)
+	}
+
+	return env
+}
+
+// buildFlags returns the build flags including the build tags.
+func (o Options) buildFlags() []string {
+	flags := append([]string{}, o.BuildFlags...)
+	if len(o.Tags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(o.Tags, ","))
+	}
+
+	return flags
+}
+
+// logger returns the Logger or one which discards everything.
+func (o Options) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+
+	return o.Logger
+}
+
+// workers returns the amount of concurrent workers, which is at least 1.
+func (o Options) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+
+	return runtime.GOMAXPROCS(0)
+}
+
+// loadMode returns the packages load mode, which only requires syntax for the dependencies if requested.
+func (o Options) loadMode() packages.LoadMode {
+	mode := packages.LoadSyntax | packages.NeedModule
+	if o.DependencySyntax {
+		mode |= packages.NeedDeps
+	}
+
+	return mode
 }
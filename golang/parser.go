@@ -15,16 +15,22 @@
 package golang
 
 import (
+	"context"
 	"fmt"
 	"github.com/golangee/reflectplus/internal/annotation"
 	"github.com/golangee/reflectplus/internal/tag"
 	"github.com/golangee/reflectplus/meta"
 	"go/ast"
+	"go/constant"
 	"go/parser"
 	"go/token"
 	"go/types"
 	"golang.org/x/tools/go/packages"
+	"math/big"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -32,93 +38,761 @@ import (
 
 type parseCtx struct {
 	fset  *token.FileSet
-	files []*ast.File
+	index *posIndex
+
+	// canonical is true, if DeclIds are not hashed, see Options.CanonicalIds
+	canonical bool
+
+	// typeParams contains the ids of type parameters, which are scoped by their declaring type or function
+	typeParams map[*types.TypeParam]meta.DeclId
+
+	// diagnostics contains the reported problems of the package
+	diagnostics []Diagnostic
+
+	// pending contains the declarations, which are under construction. A recursive reference to such a declaration
+	// is a forward reference, which is resolved as soon as the declaration is finished.
+	pending map[meta.DeclId]bool
+
+	// info contains the types of the expressions of the package, which is put into the table, or nil
+	info *types.Info
+}
+
+// fork returns a context which shares the loaded files but has its own scope of type parameters, so that it can
+// be used concurrently.
+func (c *parseCtx) fork() *parseCtx {
+	return &parseCtx{
+		fset:       c.fset,
+		index:      c.index,
+		canonical:  c.canonical,
+		typeParams: map[*types.TypeParam]meta.DeclId{},
+		pending:    map[meta.DeclId]bool{},
+	}
+}
+
+// declared returns true, if the declaration is either finished or under construction, so that a recursive type
+// refers to itself by a forward reference to the declaration under construction, instead of being constructed
+// endlessly.
+func (c *parseCtx) declared(table *meta.Table, id meta.DeclId) bool {
+	return c.pending[id] || table.HasDeclaration(id)
+}
+
+// finish ends the construction of the declaration. If the construction failed, the declaration is removed together
+// with all declarations, which have already been put with a forward reference to it, e.g. *Broken for
+// type Broken struct{ Self *Broken; Field Undefined }, so that the table contains no dangling references.
+func (c *parseCtx) finish(table *meta.Table, id meta.DeclId, err *error) {
+	delete(c.pending, id)
+	if *err != nil {
+		removeDeclarations(table, id)
+	}
+}
+
+// removeDeclarations removes the declarations and all declarations, which refer to them directly or transitively,
+// from the table and from the declarations of its packages.
+func removeDeclarations(table *meta.Table, ids ...meta.DeclId) {
+	removed := map[meta.DeclId]bool{}
+	for _, id := range ids {
+		delete(table.Declarations, id)
+		removed[id] = true
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for declId, decl := range table.Declarations {
+			for _, ref := range decl.References() {
+				if removed[ref] {
+					delete(table.Declarations, declId)
+					removed[declId] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, pkg := range table.Packages {
+		pkg.Declarations = slices.DeleteFunc(pkg.Declarations, func(declId meta.DeclId) bool {
+			return removed[declId]
+		})
+	}
+}
+
+// declId returns the unambiguous and canonical Go-like notation of a declaration either as is or hashed.
+func (c *parseCtx) declId(canonical string) meta.DeclId {
+	if c.canonical {
+		return meta.DeclId(canonical)
+	}
+
+	return meta.NewDeclId().Put(canonical).Finish()
+}
+
+// pkgDeclId returns the id of a package level declaration, like a named type, a function or a constant. They all
+// share the same namespace.
+func (c *parseCtx) pkgDeclId(pkg *types.Package, name string) meta.DeclId {
+	if pkg == nil {
+		// universe scope, e.g. error or any
+		return c.declId(name)
+	}
+
+	return c.declId(pkg.Path() + "." + name)
+}
+
+// registerTypeParams assigns the ids of the declared type parameters in the scope of their owner.
+func (c *parseCtx) registerTypeParams(owner meta.DeclId, list *types.TypeParamList) {
+	for i := 0; i < list.Len(); i++ {
+		c.typeParams[list.At(i)] = c.declId(string(owner) + "#" + list.At(i).Obj().Name())
+	}
+}
+
+// annotations parses the annotations of the doc. A malformed annotation is reported and the declaration is kept
+// without annotations.
+func (c *parseCtx) annotations(loc meta.Location, id meta.DeclId, doc string) []meta.Annotation {
+	list, err := annotation.Parse(doc)
+	if err != nil {
+		c.diagnostics = append(c.diagnostics, Diagnostic{
+			Location: loc,
+			Severity: SeverityError,
+			Kind:     DiagnosticAnnotation,
+			DeclId:   id,
+			Message:  err.Error(),
+		})
+	}
+
+	return wrapAnnotations(loc, list)
+}
+
+// joinIds concats the given ids using the separator.
+func joinIds(ids []meta.DeclId, sep string) string {
+	tmp := make([]string, 0, len(ids))
+	for _, id := range ids {
+		tmp = append(tmp, string(id))
+	}
+
+	return strings.Join(tmp, sep)
 }
 
 func NewProject(opts Options) (*Project, error) {
-	fmt.Println("dir:", opts.Dir)
-	fmt.Println("patterns:", opts.Patterns)
-	table := meta.NewTable()
-	parseCtx := &parseCtx{}
+	return NewProjectContext(context.Background(), opts)
+}
+
+// NewProjectContext works like NewProject, but stops loading and processing the packages, as soon as the context
+// is done. In that case, the error of the context is returned.
+func NewProjectContext(ctx context.Context, opts Options) (*Project, error) {
+	var prj *Project
+	var err error
+	if len(opts.Platforms) > 0 {
+		prj, err = parsePlatforms(ctx, opts)
+	} else {
+		prj, err = parseTable(ctx, opts)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	prj.diagnostics = sortDiagnostics(prj.diagnostics)
+	prj.importTable = prj.table.CreateImportTable()
+
+	return prj, nil
+}
+
+// parsePlatforms parses the table for each platform and merges them. The first declaration wins, if a declaration
+// is different between platforms. However, the methods, the implemented interfaces and the constructors of named
+// types are the union of all platforms, because they may be declared in platform specific files.
+func parsePlatforms(ctx context.Context, opts Options) (*Project, error) {
+	res := &Project{table: meta.NewTable()}
+	for _, platform := range opts.Platforms {
+		platformOpts := opts
+		platformOpts.Platforms = nil
+		platformOpts.GOOS = platform.GOOS
+		platformOpts.GOARCH = platform.GOARCH
+		platformOpts.Tags = append(append([]string{}, opts.Tags...), platform.Tags...)
+
+		prj, err := parseTable(ctx, platformOpts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+
+		table := prj.table
+		res.table.Merge(table)
+		res.stats.add(prj.stats)
+		res.diagnostics = append(res.diagnostics, prj.diagnostics...)
+
+		for _, id := range table.DeclIds() {
+			mergePlatform(res.table.Declarations[id], table.Declarations[id], platform)
+		}
+	}
+
+	return res, nil
+}
+
+// mergePlatform records the platform of the declaration src at the merged declaration dst and adds the platform
+// specific lists of src.
+func mergePlatform(dst, src meta.Type, platform meta.Platform) {
+	switch {
+	case dst.Named != nil && src.Named != nil:
+		dst.Named.Platforms = append(dst.Named.Platforms, platform)
+		if dst.Named == src.Named {
+			return
+		}
+
+		dst.Named.Methods = appendMissing(dst.Named.Methods, src.Named.Methods)
+		dst.Named.ValueMethodSet = appendMissing(dst.Named.ValueMethodSet, src.Named.ValueMethodSet)
+		dst.Named.PointerMethodSet = appendMissing(dst.Named.PointerMethodSet, src.Named.PointerMethodSet)
+		dst.Named.Constructors = appendMissing(dst.Named.Constructors, src.Named.Constructors)
+		for _, impl := range src.Named.Implements {
+			known := slices.ContainsFunc(dst.Named.Implements, func(other meta.Implementation) bool {
+				return other.DeclId == impl.DeclId
+			})
+
+			if !known {
+				dst.Named.Implements = append(dst.Named.Implements, impl)
+			}
+		}
+	case dst.Const != nil && src.Const != nil:
+		dst.Const.Platforms = append(dst.Const.Platforms, platform)
+	case dst.Var != nil && src.Var != nil:
+		dst.Var.Platforms = append(dst.Var.Platforms, platform)
+	case dst.Alias != nil && src.Alias != nil:
+		dst.Alias.Platforms = append(dst.Alias.Platforms, platform)
+	}
+}
+
+// appendMissing appends those ids to list, which are not already contained.
+func appendMissing(list []meta.DeclId, ids []meta.DeclId) []meta.DeclId {
+	for _, id := range ids {
+		if !slices.Contains(list, id) {
+			list = append(list, id)
+		}
+	}
+
+	return list
+}
+
+// parseTable loads the packages of a single build configuration. Only the table, the stats and the diagnostics of
+// the project are set.
+func parseTable(ctx context.Context, opts Options) (*Project, error) {
+	logger := opts.logger()
+	logger.Debug("parsing", "dir", opts.Dir, "patterns", opts.Patterns)
+	progress := newProgressReporter(opts.Progress)
+	stats := Stats{}
+	pctx := &parseCtx{
+		canonical:  opts.CanonicalIds,
+		typeParams: map[*types.TypeParam]meta.DeclId{},
+		pending:    map[meta.DeclId]bool{},
+	}
 	mtx := sync.Mutex{}
 	cfg := &packages.Config{
-		Mode:    packages.LoadAllSyntax | packages.NeedModule,
-		Context: nil,
+		Mode:    opts.loadMode(),
+		Context: ctx,
 		Logf: func(format string, args ...interface{}) {
-			fmt.Printf(format, args...)
-			fmt.Println()
+			logger.Debug(fmt.Sprintf(format, args...))
 		},
 		Dir:        opts.Dir,
-		Env:        nil,
-		BuildFlags: nil,
+		Env:        opts.environ(),
+		BuildFlags: opts.buildFlags(),
 		Fset:       token.NewFileSet(),
 		ParseFile: func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
-			//TODO remove func body ast to speed up parsing
+			// the build system continues with the other packages, so the remaining files are skipped quickly
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
 			const mode = parser.AllErrors | parser.ParseComments
 			file, err := parser.ParseFile(fset, filename, src, mode)
 			if err != nil {
 				return nil, err
 			}
 
+			bodies, bytes := 0, 0
+			if !opts.KeepFuncBodies {
+				bodies, bytes = stripFuncBodies(file)
+			}
+
+			idx := indexFile(fset, file)
+
 			mtx.Lock()
 			defer mtx.Unlock()
 
-			parseCtx.files = append(parseCtx.files, file)
+			pctx.index.merge(idx)
+			stats.Files++
+			stats.StrippedBodies += bodies
+			stats.StrippedBytes += bytes
 			return file, nil
 		},
-		Tests:   false,
-		Overlay: nil,
+		Tests:   opts.Tests,
+		Overlay: opts.Overlay,
+	}
+	pctx.fset = cfg.Fset
+	pctx.index = newPosIndex(cfg.Fset)
+
+	// without a cache, all patterns are parsed, otherwise only the outdated packages
+	var cache *tableCache
+	var cached []pkgTable
+	patterns := opts.Patterns
+	outdated := map[string]string{}
+	if opts.CacheDir != "" {
+		c, err := newTableCache(opts)
+		if err != nil {
+			return nil, err
+		}
+		cache = c
+
+		metaCfg := *cfg
+		metaCfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps |
+			packages.NeedModule
+		metaCfg.ParseFile = nil
+		roots, err := loadPackages(&metaCfg, opts.Patterns, opts.Tests)
+		if err != nil {
+			return nil, err
+		}
+
+		progress.reportAll(PhaseLoad, len(roots))
+		progress.start(PhaseTable, len(roots))
+
+		patterns = nil
+		for _, root := range roots {
+			key, err := cache.key(root)
+			if err != nil {
+				return nil, err
+			}
+
+			if table, ok := cache.load(key); ok {
+				cached = append(cached, newPkgTable(root, table))
+				stats.CachedPackages++
+				progress.report(PhaseTable, root.ID, true)
+				continue
+			}
+
+			if !slices.Contains(patterns, root.PkgPath) {
+				patterns = append(patterns, root.PkgPath)
+			}
+			outdated[root.ID] = key
+		}
+	}
+
+	var parsed []pkgTable
+	if cache == nil || len(outdated) > 0 {
+		pkgs, err := loadPackages(cfg, patterns, opts.Tests)
+		if err != nil {
+			return nil, err
+		}
+
+		if !opts.KeepFuncBodies {
+			dropUnusedImportErrors(pkgs)
+		}
+
+		if cache != nil {
+			// the patterns of the outdated packages may also match up-to-date test variants
+			var tmp []*packages.Package
+			for _, pkg := range pkgs {
+				if _, ok := outdated[pkg.ID]; ok {
+					tmp = append(tmp, pkg)
+				}
+			}
+			pkgs = tmp
+		} else {
+			progress.reportAll(PhaseLoad, len(pkgs))
+			progress.start(PhaseTable, len(pkgs))
+		}
+
+		progress.reportAll(PhaseTypeCheck, len(pkgs))
+
+		parsed, err = putPackages(ctx, pctx, pkgs, opts.workers(), progress)
+		if err != nil {
+			return nil, err
+		}
+
+		if cache != nil {
+			for _, p := range parsed {
+				// the diagnostics are not cached, so that they are reported again
+				if len(p.diagnostics) > 0 {
+					continue
+				}
+
+				if err := cache.store(outdated[p.id], p.table); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	var diagnostics []Diagnostic
+	for _, p := range parsed {
+		diagnostics = append(diagnostics, p.diagnostics...)
+	}
+
+	logger.Debug("parsed", "files", stats.Files, "cachedPackages", stats.CachedPackages,
+		"strippedBodies", stats.StrippedBodies, "diagnostics", len(diagnostics))
+
+	tables := append(cached, parsed...)
+	table := mergeTables(tables)
+	if opts.Scoped {
+		var rootPaths []string
+		for _, t := range tables {
+			rootPaths = append(rootPaths, t.path)
+		}
+
+		applyScope(table, rootPaths, opts.ScopeDepth)
+	}
+
+	putImplementations(table)
+	putConstructors(table)
+
+	return &Project{table: table, stats: stats, diagnostics: diagnostics}, nil
+}
+
+// A pkgTable contains the declarations of a single package and those of its dependencies, which are referenced.
+type pkgTable struct {
+	id      string
+	path    string
+	imports []string
+	table   *meta.Table
+
+	// diagnostics of the package, which are never cached
+	diagnostics []Diagnostic
+}
+
+func newPkgTable(pkg *packages.Package, table *meta.Table) pkgTable {
+	imports := make([]string, 0, len(pkg.Imports))
+	for _, imp := range pkg.Imports {
+		imports = append(imports, imp.ID)
+	}
+
+	return pkgTable{id: pkg.ID, path: pkg.PkgPath, imports: imports, table: table}
+}
+
+// loadPackages loads the packages of the patterns and replaces them by their test variants, if required.
+func loadPackages(cfg *packages.Config, patterns []string, tests bool) ([]*packages.Package, error) {
+	pkgs, err := packages.Load(cfg, patterns...)
+
+	// a cancellation is either not wrapped or only reported by the packages, e.g. by the skipped files
+	if cfg.Context != nil && cfg.Context.Err() != nil {
+		return nil, cfg.Context.Err()
 	}
-	parseCtx.fset = cfg.Fset
 
-	//pkgs, err := packages.Load(cfg, "github.com/worldiety/mercurius/...")
-	pkgs, err := packages.Load(cfg, opts.Patterns...)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, pkg := range pkgs {
-		/*for expr, tv := range pkg.TypesInfo.Declarations{
-			posn := cfg.Fset.Position(expr.Pos())
-			tvstr := tv.Type.String()
-			if tv.Value != nil {
-				tvstr += " = " + tv.Value.String()
+	if tests {
+		pkgs = testVariants(pkgs)
+	}
+
+	return pkgs, nil
+}
+
+// putPackages creates a table for each package concurrently, because packages are independent of each other. The
+// table of a package, which cannot be represented, is empty, so that it does not block the others.
+func putPackages(ctx context.Context, pctx *parseCtx, pkgs []*packages.Package, workers int,
+	progress *progressReporter) ([]pkgTable, error) {
+	res := make([]pkgTable, len(pkgs))
+	sem := make(chan struct{}, workers)
+	wg := sync.WaitGroup{}
+	for i, pkg := range pkgs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
 			}
-			if mode(tv)!="type"{
-			//	continue
+
+			res[i] = newPkgTable(pkg, meta.NewTable())
+			pkgCtx := pctx.fork()
+			pkgCtx.info = pkg.TypesInfo
+			// the table is kept, although it may be partial, because the failed declarations have been removed
+			if pkg.TypesInfo != nil && putPackage(ctx, res[i].table, pkgCtx, pkg) == nil {
+				pkgCtx.validate(res[i].table)
 			}
-			if !strings.Contains(tvstr,"ProviderType"){
-				continue
+
+			res[i].diagnostics = packageDiagnostics(pkgCtx, pkg)
+			for _, d := range pkgCtx.diagnostics {
+				d.Package = pkg.ID
+				res[i].diagnostics = append(res[i].diagnostics, d)
+			}
+
+			progress.report(PhaseTable, pkg.ID, false)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// mergeTables merges the tables in a stable order. The declarations of a package are always taken from its own
+// table, because other tables may only contain a variant without docs, loaded from the export data. Imported
+// packages are merged before their importers, so that an underlying type, which is shared with a type definition
+// of an importer, is taken from the package, which has declared it.
+func mergeTables(tables []pkgTable) *meta.Table {
+	depths := importDepths(tables)
+	sort.Slice(tables, func(i, j int) bool {
+		if depths[tables[i].id] != depths[tables[j].id] {
+			return depths[tables[i].id] < depths[tables[j].id]
+		}
+
+		return tables[i].id < tables[j].id
+	})
+
+	res := meta.NewTable()
+	for _, t := range tables {
+		res.MergePackage(t.table, t.path)
+	}
+
+	for _, t := range tables {
+		res.Merge(t.table)
+	}
+
+	return res
+}
+
+// importDepths returns the length of the longest import chain of each package to the other given packages.
+func importDepths(tables []pkgTable) map[string]int {
+	imports := map[string][]string{}
+	for _, t := range tables {
+		imports[t.id] = t.imports
+	}
+
+	depths := map[string]int{}
+	var depth func(id string) int
+	depth = func(id string) int {
+		if d, ok := depths[id]; ok {
+			return d
+		}
+
+		// imports are acyclic, but the marker keeps a broken graph from recursing endlessly
+		depths[id] = 0
+		d := 0
+		for _, imp := range imports[id] {
+			if _, ok := imports[imp]; ok {
+				d = max(d, depth(imp)+1)
 			}
-			// line:col | expr | mode : type = value
-			fmt.Fprintf(os.Stdout, "%4d:%4d | %-30s | %-7s : %s\n",
-				posn.Line, posn.Column, exprString(cfg.Fset, expr),
-				mode(tv), tvstr)
+		}
+		depths[id] = d
+
+		return d
+	}
+
+	for _, t := range tables {
+		depth(t.id)
+	}
 
-		}*/
+	return depths
+}
+
+// putPackage puts all type declarations and the exported package level functions, constants and variables of the
+// package into the table, in the order of their declaration. A declaration, which cannot be represented, is
+// reported and skipped together with the declarations which depend on it, but the rest of the package is kept. A
+// cancellation aborts the package, but is not reported.
+func putPackage(ctx context.Context, table *meta.Table, pctx *parseCtx, pkg *packages.Package) error {
+	idents := make([]*ast.Ident, 0, len(pkg.TypesInfo.Defs))
+	for ident := range pkg.TypesInfo.Defs {
+		idents = append(idents, ident)
+	}
+
+	// the files are parsed concurrently, so only the positions within a file are ordered
+	sort.Slice(idents, func(i, j int) bool {
+		fileI, fileJ := pctx.fset.File(idents[i].Pos()).Name(), pctx.fset.File(idents[j].Pos()).Name()
+		if fileI != fileJ {
+			return fileI < fileJ
+		}
+
+		return idents[i].Pos() < idents[j].Pos()
+	})
+
+	for _, a := range idents {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-		for a, b := range pkg.TypesInfo.Defs {
-			/*if !strings.Contains(a.Name, "ProviderType") {
+		b := pkg.TypesInfo.Defs[a]
+		if a.Obj == nil || b == nil {
+			continue
+		}
+
+		// an unsupported declaration is only reported and skipped, so that the rest of the package is still usable
+		switch a.Obj.Decl.(type) {
+		case *ast.TypeSpec:
+			if _, err := putType(table, pctx, b.Type()); err != nil {
+				pctx.unsupported(b, err)
+			}
+		case *ast.ValueSpec:
+			// local values are also resolved, but we only want the package level ones
+			if !b.Exported() || b.Parent() != pkg.Types.Scope() {
 				continue
-			}*/
-			if a.Obj != nil {
-				if _, ok := a.Obj.Decl.(*ast.TypeSpec); ok {
-					//addType(table, cfg.Fset, b)
-					_, err := putType(table, parseCtx, b.Type())
-					if err != nil {
-						return nil, err
-					}
+			}
+
+			switch obj := b.(type) {
+			case *types.Const:
+				if _, err := putConst(table, pctx, obj); err != nil {
+					pctx.unsupported(b, err)
+				}
+			case *types.Var:
+				if _, err := putVar(table, pctx, obj); err != nil {
+					pctx.unsupported(b, err)
+				}
+			}
+		case *ast.FuncDecl:
+			// methods are not resolved into the package scope, so this is always a package level function
+			if fun, ok := b.(*types.Func); ok && fun.Exported() {
+				if _, err := putFunc(table, pctx, fun); err != nil {
+					pctx.unsupported(b, err)
 				}
 			}
+		}
+	}
+
+	return nil
+}
+
+// unsupported reports the failed declaration.
+func (c *parseCtx) unsupported(obj types.Object, err error) {
+	pos := c.fset.Position(obj.Pos())
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Location: meta.NewLocation(pos.Filename, pos.Line, pos.Column),
+		Severity: SeverityError,
+		Kind:     DiagnosticUnsupported,
+		DeclId:   c.objDeclId(obj),
+		Message:  fmt.Sprintf("%s: %s", obj.Name(), err),
+	})
+}
+
+// validate reports each unresolved forward reference and any other inconsistency of the table, which would
+// otherwise cause a panic, when the table is used. The invalid declarations are removed, see removeDeclarations.
+func (c *parseCtx) validate(table *meta.Table) {
+	errs, ok := table.Validate().(meta.ValidationErrors)
+	if !ok {
+		return
+	}
 
+	var invalid []meta.DeclId
+	for _, e := range errs {
+		invalid = append(invalid, e.DeclId)
+
+		var loc meta.Location
+		if named := table.Declarations[e.DeclId].Named; named != nil {
+			loc = named.Location
 		}
 
+		c.diagnostics = append(c.diagnostics, Diagnostic{
+			Location: loc,
+			Severity: SeverityError,
+			Kind:     DiagnosticUnsupported,
+			DeclId:   e.DeclId,
+			Message:  e.Error(),
+		})
 	}
 
-	prj := &Project{table: table}
-	prj.importTable = prj.table.CreateImportTable()
+	removeDeclarations(table, invalid...)
+}
 
-	return prj, nil
+// stripFuncBodies replaces the bodies of all function and method declarations by a single panic(nil) and removes
+// their comments, which is fine, because only the declarations are inspected. The panic is a terminating statement,
+// so that the type checker neither complains about a missing body nor about a missing return. However, imports
+// which are only used within bodies are reported as unused, see dropUnusedImportErrors. Function literals are kept,
+// because a package level variable may be initialized by them. Returns the amount of stripped bodies and their size
+// in bytes.
+func stripFuncBodies(file *ast.File) (bodies, bytes int) {
+	var stripped []*ast.BlockStmt
+	for _, decl := range file.Decls {
+		if fun, ok := decl.(*ast.FuncDecl); ok && fun.Body != nil {
+			stripped = append(stripped, fun.Body)
+			bodies++
+			bytes += int(fun.Body.End() - fun.Body.Pos())
+			// a valid position is required, because lookups by token.NoPos must not find the artificial nodes
+			pos := fun.Body.Lbrace + 1
+			fun.Body = &ast.BlockStmt{
+				Lbrace: fun.Body.Lbrace,
+				List: []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{
+					Fun:    &ast.Ident{NamePos: pos, Name: "panic"},
+					Lparen: pos,
+					Args:   []ast.Expr{&ast.Ident{NamePos: pos, Name: "nil"}},
+					Rparen: pos,
+				}}},
+				Rbrace: fun.Body.Rbrace,
+			}
+		}
+	}
+
+	if len(stripped) == 0 {
+		return
+	}
+
+	// comments are sorted, just like the declarations
+	comments := file.Comments[:0]
+	for _, group := range file.Comments {
+		for len(stripped) > 0 && stripped[0].End() <= group.Pos() {
+			stripped = stripped[1:]
+		}
+
+		if len(stripped) > 0 && group.Pos() > stripped[0].Pos() && group.End() < stripped[0].End() {
+			continue
+		}
+
+		comments = append(comments, group)
+	}
+	file.Comments = comments
+
+	return
+}
+
+// dropUnusedImportErrors removes the unused import errors of the packages and their dependencies, because they are
+// caused by stripped function bodies and not by the sources.
+func dropUnusedImportErrors(pkgs []*packages.Package) {
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		typeErrors := pkg.TypeErrors[:0]
+		for _, err := range pkg.TypeErrors {
+			if !err.Soft || !unusedImport(err.Msg) {
+				typeErrors = append(typeErrors, err)
+			}
+		}
+		pkg.TypeErrors = typeErrors
+
+		errs := pkg.Errors[:0]
+		for _, err := range pkg.Errors {
+			if err.Kind != packages.TypeError || !unusedImport(err.Msg) {
+				errs = append(errs, err)
+			}
+		}
+		pkg.Errors = errs
+	})
+}
+
+// unusedImport returns true for the message of the type checker, e.g. "fmt" imported and not used or
+// "math/rand" imported as r and not used.
+func unusedImport(msg string) bool {
+	return strings.HasPrefix(msg, `"`) && strings.Contains(msg, `" imported `) && strings.HasSuffix(msg, " and not used")
+}
+
+// testVariants replaces each package by its test variant, which also contains the declarations of the _test.go
+// files. The generated test main packages are removed.
+func testVariants(pkgs []*packages.Package) []*packages.Package {
+	hasVariant := map[string]bool{}
+	for _, pkg := range pkgs {
+		if pkg.ID == pkg.PkgPath+" ["+pkg.PkgPath+".test]" {
+			hasVariant[pkg.PkgPath] = true
+		}
+	}
+
+	var res []*packages.Package
+	for _, pkg := range pkgs {
+		if pkg.ID == pkg.PkgPath && hasVariant[pkg.PkgPath] {
+			continue
+		}
+
+		if pkg.Name == "main" && strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue
+		}
+
+		res = append(res, pkg)
+	}
+
+	return res
 }
 
 func putType(table *meta.Table, fset *parseCtx, typ types.Type) (meta.DeclId, error) {
@@ -143,6 +817,12 @@ func putType(table *meta.Table, fset *parseCtx, typ types.Type) (meta.DeclId, er
 		return putChan(table, fset, t)
 	case *types.Interface:
 		return putInterface(table, fset, t)
+	case *types.Alias:
+		return putAlias(table, fset, t)
+	case *types.TypeParam:
+		return putTypeParam(table, fset, t)
+	case *types.Union:
+		return putUnion(table, fset, t)
 	default:
 		panic(reflect.TypeOf(t))
 	}
@@ -155,13 +835,17 @@ func putChan(table *meta.Table, fset *parseCtx, obj *types.Chan) (meta.DeclId, e
 	}
 
 	myDir := meta.ChanDir("")
+	prefix := ""
 	switch obj.Dir() {
 	case types.SendRecv:
 		myDir = meta.SendRecv
+		prefix = "chan "
 	case types.RecvOnly:
 		myDir = meta.RecvOnly
+		prefix = "<-chan "
 	case types.SendOnly:
 		myDir = meta.SendOnly
+		prefix = "chan<- "
 	default:
 		panic("invalid chan dir:" + strconv.Itoa(int(obj.Dir())))
 	}
@@ -171,7 +855,7 @@ func putChan(table *meta.Table, fset *parseCtx, obj *types.Chan) (meta.DeclId, e
 		DeclId:  tQual,
 	}
 
-	id := meta.NewDeclId().Put(res.DeclId, obj.Dir()).Finish()
+	id := fset.declId(prefix + string(res.DeclId))
 	table.PutDeclaration(id, meta.Type{
 		Channel: res,
 	})
@@ -195,7 +879,7 @@ func putMap(table *meta.Table, fset *parseCtx, obj *types.Map) (meta.DeclId, err
 		Value: vQual,
 	}
 
-	q := meta.NewDeclId().Put("map", res.Key, res.Value).Finish()
+	q := fset.declId("map[" + string(res.Key) + "]" + string(res.Value))
 	table.PutDeclaration(q, meta.Type{
 		Map: res,
 	})
@@ -206,68 +890,365 @@ func putMap(table *meta.Table, fset *parseCtx, obj *types.Map) (meta.DeclId, err
 func putSignature(table *meta.Table, fset *parseCtx, obj *types.Signature) (meta.DeclId, error) {
 	res := &meta.Signature{}
 
-	builder := meta.NewDeclId()
-	builder.Put("signature")
+	sb := &strings.Builder{}
+	sb.WriteString("func")
+
+	if obj.Recv() != nil {
+		rQual, err := putType(table, fset, obj.Recv().Type())
+		if err != nil {
+			return "", err
+		}
+
+		res.Receiver = &meta.Param{
+			Name:   obj.Recv().Name(),
+			DeclId: rQual,
+		}
+
+		sb.WriteString(" (" + paramString(*res.Receiver, "") + ") ")
+	}
+
+	typeParams, err := putTypeParams(table, fset, obj.TypeParams())
+	if err != nil {
+		return "", err
+	}
+
+	res.TypeParams = typeParams
+	if len(typeParams) > 0 {
+		sb.WriteString("[" + joinIds(typeParams, ", ") + "]")
+	}
+
+	params, results, notation, err := putParams(table, fset, obj)
+	if err != nil {
+		return "", err
+	}
+
+	res.Params = params
+	res.Results = results
+	sb.WriteString(notation)
+
+	res.Variadic = obj.Variadic()
+
+	q := fset.declId(sb.String())
+
+	table.PutDeclaration(q, meta.Type{
+		Signature: res,
+	})
+
+	return q, nil
+}
+
+// putParams declares the parameter and result types of the given signature and returns them together with their
+// canonical notation, e.g. (a int, b ...string) (error).
+func putParams(table *meta.Table, fset *parseCtx, obj *types.Signature) ([]meta.Param, []meta.Param, string, error) {
+	var resParams, resResults []meta.Param
+
+	var params []string
 	for i := 0; i < obj.Params().Len(); i++ {
 		param := obj.Params().At(i)
 		pQual, err := putType(table, fset, param.Type())
 		if err != nil {
-			return "", err
+			return nil, nil, "", err
 		}
 
 		p := meta.Param{
 			Name:   param.Name(),
 			DeclId: pQual,
 		}
-		res.Params = append(res.Params, p)
+		resParams = append(resParams, p)
+
+		if obj.Variadic() && i == obj.Params().Len()-1 {
+			// the type of a variadic parameter is a slice
+			elemQual, err := putType(table, fset, param.Type().(*types.Slice).Elem())
+			if err != nil {
+				return nil, nil, "", err
+			}
+
+			params = append(params, paramString(p, elemQual))
+		} else {
+			params = append(params, paramString(p, ""))
+		}
+	}
+
+	var results []string
+	for i := 0; i < obj.Results().Len(); i++ {
+		param := obj.Results().At(i)
+		pQual, err := putType(table, fset, param.Type())
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		p := meta.Param{
+			Name:   param.Name(),
+			DeclId: pQual,
+		}
+		resResults = append(resResults, p)
+		results = append(results, paramString(p, ""))
+	}
+
+	notation := "(" + strings.Join(params, ", ") + ")"
+	if len(results) > 0 {
+		notation += " (" + strings.Join(results, ", ") + ")"
+	}
+
+	return resParams, resResults, notation, nil
+}
+
+// paramString returns the canonical notation of a parameter. If variadicElem is not empty, the parameter is
+// written as variadic.
+func paramString(p meta.Param, variadicElem meta.DeclId) string {
+	typ := string(p.DeclId)
+	if variadicElem != "" {
+		typ = "..." + string(variadicElem)
+	}
+
+	if p.Name == "" {
+		return typ
+	}
+
+	return p.Name + " " + typ
+}
+
+func putFunc(table *meta.Table, fset *parseCtx, obj *types.Func) (_ meta.DeclId, err error) {
+	pos := fset.fset.Position(obj.Pos())
+
+	pkgImportPath := ""
+	pkgName := ""
+
+	if obj.Pkg() != nil {
+		pkgImportPath = obj.Pkg().Path()
+		pkgName = obj.Pkg().Name()
+	}
+
+	// methods are identified by their receiver, so that equally named methods of different types or
+	// interfaces never collide with each other or with a package level function
+	var recvQual meta.DeclId
+	var qualifier meta.DeclId
+	pointerRecv := false
+	if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+		recvType := recv.Type()
+		if ptr, ok := recvType.(*types.Pointer); ok {
+			recvType = ptr.Elem()
+			pointerRecv = true
+		}
+
+		if named, ok := recvType.(*types.Named); ok && obj.Origin() == obj {
+			// methods of generic types declare their own receiver type parameters, but belong to the origin
+			recvType = named.Origin()
+		}
+
+		if iface, ok := recvType.(*types.Interface); ok {
+			// the id of an anonymous interface depends on its methods, see putInterface
+			qualifier = fset.declId(pkgImportPath + ".(" + types.TypeString(iface, nil) + ")." + obj.Name())
+		} else {
+			q, err := putType(table, fset, recvType)
+			if err != nil {
+				return "", err
+			}
+
+			recvQual = q
+			qualifier = fset.declId(string(recvQual) + "." + obj.Name())
+		}
+	} else {
+		qualifier = fset.pkgDeclId(obj.Pkg(), obj.Name())
+	}
+
+	if fset.declared(table, qualifier) {
+		return qualifier, nil
+	}
+
+	// the receiver of a method in an anonymous interface is the interface itself
+	fset.pending[qualifier] = true
+	defer fset.finish(table, qualifier, &err)
+
+	sig := obj.Type().(*types.Signature)
+	fset.registerTypeParams(qualifier, sig.TypeParams())
+	fset.registerTypeParams(qualifier, sig.RecvTypeParams())
+
+	loc := meta.NewLocation(pos.Filename, pos.Line, pos.Column)
+
+	s := fset.index.doc(obj.Pos())
+	uQual, err := putType(table, fset, obj.Type().Underlying())
+	if err != nil {
+		return "", err
+	}
+
+	table.PutNamedDeclaration(pkgImportPath, pkgName, qualifier, &meta.Named{
+		Location:        loc,
+		Doc:             s,
+		Annotations:     fset.annotations(loc, qualifier, s),
+		Underlying:      uQual,
+		Name:            obj.Name(),
+		Func:            true,
+		Receiver:        recvQual,
+		PointerReceiver: pointerRecv,
+		BuildConstraint: fset.index.buildConstraint(obj.Pos()),
+	})
+
+	return qualifier, nil
+}
+
+// constValue returns the exact value in Go notation. The value of a float is a float literal, because the fraction
+// of ExactString, like 157/50 for 3.14, would be an integer division.
+func constValue(val constant.Value) string {
+	switch val.Kind() {
+	case constant.Float:
+		return floatLiteral(val)
+	case constant.Complex:
+		return "complex(" + floatLiteral(constant.Real(val)) + ", " + floatLiteral(constant.Imag(val)) + ")"
+	default:
+		return val.ExactString()
+	}
+}
+
+// floatLiteral returns a decimal float literal, like 2.0, 3.14 or 6.02214076e23, if the value has a finite
+// decimal representation. Otherwise, it returns an exact float division, like 1.0/3. A value with a huge exponent,
+// like 1e5000, is no fraction anymore, so its shortest decimal literal is returned instead.
+func floatLiteral(val constant.Value) string {
+	numVal, denVal := constant.Num(val), constant.Denom(val)
+	if numVal.Kind() == constant.Unknown || denVal.Kind() == constant.Unknown {
+		return approxFloatLiteral(val)
+	}
+
+	num, okNum := new(big.Int).SetString(numVal.ExactString(), 10)
+	den, okDen := new(big.Int).SetString(denVal.ExactString(), 10)
+	if !okNum || !okDen {
+		return approxFloatLiteral(val)
+	}
+
+	// the decimal representation is finite, if the denominator has no other prime factors than 2 and 5
+	rest := new(big.Int).Set(den)
+	twos, fives := 0, 0
+	for ; rest.Bit(0) == 0; twos++ {
+		rest.Rsh(rest, 1)
+	}
+	for five := big.NewInt(5); new(big.Int).Mod(rest, five).Sign() == 0; fives++ {
+		rest.Quo(rest, five)
+	}
+
+	if rest.Cmp(big.NewInt(1)) != 0 {
+		return num.String() + ".0/" + den.String()
+	}
+
+	// val is digits * 10^exp
+	exp := -max(twos, fives)
+	mantissa := new(big.Int).Mul(num, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-exp)), nil))
+	mantissa.Quo(mantissa, den)
+
+	sign := ""
+	if mantissa.Sign() < 0 {
+		sign = "-"
+		mantissa.Neg(mantissa)
+	}
+
+	digits := mantissa.String()
+	for len(digits) > 1 && digits[len(digits)-1] == '0' {
+		digits = digits[:len(digits)-1]
+		exp++
+	}
+
+	switch {
+	case exp >= 0 && len(digits)+exp <= 21:
+		return sign + digits + strings.Repeat("0", exp) + ".0"
+	case exp < 0 && -exp < len(digits):
+		return sign + digits[:len(digits)+exp] + "." + digits[len(digits)+exp:]
+	case exp < 0 && -exp-len(digits) < 6:
+		return sign + "0." + strings.Repeat("0", -exp-len(digits)) + digits
+	default:
+		// the scientific notation of the first digit, like 1e-300
+		fraction := ""
+		if len(digits) > 1 {
+			fraction = "." + digits[1:]
+		}
+
+		return sign + digits[:1] + fraction + "e" + strconv.Itoa(exp+len(digits)-1)
+	}
+}
+
+// approxFloatLiteral returns the shortest decimal literal of a float, which is not represented as a fraction,
+// like 1e+5000, or the exact string, if even that is not available.
+func approxFloatLiteral(val constant.Value) string {
+	f, ok := constant.Val(val).(*big.Float)
+	if !ok {
+		return val.ExactString()
+	}
+
+	s := f.Text('g', -1)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+
+	return s
+}
+
+func putConst(table *meta.Table, fset *parseCtx, obj *types.Const) (meta.DeclId, error) {
+	pos := fset.fset.Position(obj.Pos())
+
+	pkgImportPath := ""
+	pkgName := ""
 
-		builder.Put(p.Name, p.DeclId)
+	if obj.Pkg() != nil {
+		pkgImportPath = obj.Pkg().Path()
+		pkgName = obj.Pkg().Name()
 	}
 
-	for i := 0; i < obj.Results().Len(); i++ {
-		param := obj.Results().At(i)
-		pQual, err := putType(table, fset, param.Type())
-		if err != nil {
-			return "", err
-		}
+	qualifier := fset.pkgDeclId(obj.Pkg(), obj.Name())
 
-		p := meta.Param{
-			Name:   param.Name(),
-			DeclId: pQual,
-		}
-		res.Results = append(res.Results, p)
+	if table.HasDeclaration(qualifier) {
+		return qualifier, nil
+	}
 
-		builder.Put(p.Name, p.DeclId)
+	loc := meta.NewLocation(pos.Filename, pos.Line, pos.Column)
+
+	res := &meta.Const{
+		Location:        loc,
+		Name:            obj.Name(),
+		Value:           constValue(obj.Val()),
+		BuildConstraint: fset.index.buildConstraint(obj.Pos()),
 	}
 
-	res.Variadic = obj.Variadic()
-	builder.Put(res.Variadic)
+	switch obj.Val().Kind() {
+	case constant.Bool:
+		res.Kind = meta.ConstBool
+	case constant.String:
+		res.Kind = meta.ConstString
+	case constant.Int:
+		res.Kind = meta.ConstInt
+	case constant.Float:
+		res.Kind = meta.ConstFloat
+	case constant.Complex:
+		res.Kind = meta.ConstComplex
+	default:
+		return "", fmt.Errorf("%s: unknown constant value", loc)
+	}
 
-	if obj.Recv() != nil {
-		rQual, err := putType(table, fset, obj.Recv().Type())
-		if err != nil {
-			return "", err
+	genDecl, spec := fset.index.valueSpec(obj.Pos())
+	if genDecl != nil {
+		res.Doc = strings.TrimSpace(genDecl.Doc.Text() + spec.Doc.Text())
+		blockPos := fset.fset.Position(genDecl.Pos())
+		res.Block = meta.NewLocation(blockPos.Filename, blockPos.Line, blockPos.Column)
+		for i, s := range genDecl.Specs {
+			if s == spec {
+				res.Iota = i
+			}
 		}
+	}
 
-		res.Receiver = &meta.Param{
-			Name:   obj.Recv().Name(),
-			DeclId: rQual,
-		}
+	res.Annotations = fset.annotations(loc, qualifier, res.Doc)
 
-		builder.Put(res.Receiver.Name)
-		builder.Put(res.Receiver.DeclId)
+	tQual, err := putType(table, fset, obj.Type())
+	if err != nil {
+		return "", err
 	}
+	res.DeclId = tQual
 
-	q := builder.Finish()
-
-	table.PutDeclaration(q, meta.Type{
-		Signature: res,
+	table.PutPackageDeclaration(pkgImportPath, pkgName, qualifier, meta.Type{
+		Const: res,
 	})
 
-	return q, nil
+	return qualifier, nil
 }
 
-func putFunc(table *meta.Table, fset *parseCtx, obj *types.Func) (meta.DeclId, error) {
+func putVar(table *meta.Table, fset *parseCtx, obj *types.Var) (meta.DeclId, error) {
 	pos := fset.fset.Position(obj.Pos())
 
 	pkgImportPath := ""
@@ -278,7 +1259,7 @@ func putFunc(table *meta.Table, fset *parseCtx, obj *types.Func) (meta.DeclId, e
 		pkgName = obj.Pkg().Name()
 	}
 
-	qualifier := meta.NewDeclId().Put("func", pkgImportPath, pkgName, obj.Name()).Finish()
+	qualifier := fset.pkgDeclId(obj.Pkg(), obj.Name())
 
 	if table.HasDeclaration(qualifier) {
 		return qualifier, nil
@@ -286,23 +1267,27 @@ func putFunc(table *meta.Table, fset *parseCtx, obj *types.Func) (meta.DeclId, e
 
 	loc := meta.NewLocation(pos.Filename, pos.Line, pos.Column)
 
-	s := findTypeComment(fset, obj.Pos())
-	annotations, err := annotation.Parse(s)
-	if err != nil {
-		return "", fmt.Errorf("%s: %w", loc, err)
+	res := &meta.Var{
+		Location:        loc,
+		Name:            obj.Name(),
+		BuildConstraint: fset.index.buildConstraint(obj.Pos()),
 	}
 
-	uQual, err := putType(table, fset, obj.Type().Underlying())
+	genDecl, spec := fset.index.valueSpec(obj.Pos())
+	if genDecl != nil {
+		res.Doc = strings.TrimSpace(genDecl.Doc.Text() + spec.Doc.Text())
+	}
+
+	res.Annotations = fset.annotations(loc, qualifier, res.Doc)
+
+	tQual, err := putType(table, fset, obj.Type())
 	if err != nil {
 		return "", err
 	}
+	res.DeclId = tQual
 
-	table.PutNamedDeclaration(pkgImportPath, pkgName, qualifier, &meta.Named{
-		Location:    loc,
-		Doc:         s,
-		Annotations: wrapAnnotations(loc, annotations),
-		Underlying:  uQual,
-		Name:        obj.Name(),
+	table.PutPackageDeclaration(pkgImportPath, pkgName, qualifier, meta.Type{
+		Var: res,
 	})
 
 	return qualifier, nil
@@ -311,8 +1296,6 @@ func putFunc(table *meta.Table, fset *parseCtx, obj *types.Func) (meta.DeclId, e
 func putInterface(table *meta.Table, fset *parseCtx, obj *types.Interface) (meta.DeclId, error) {
 	res := &meta.Interface{}
 
-	builder := meta.NewDeclId()
-	builder.Put("interface")
 	for i := 0; i < obj.NumMethods(); i++ {
 		methodQualifier, err := putFunc(table, fset, obj.Method(i))
 		if err != nil {
@@ -320,7 +1303,6 @@ func putInterface(table *meta.Table, fset *parseCtx, obj *types.Interface) (meta
 		}
 
 		res.AllMethods = append(res.AllMethods, methodQualifier)
-		builder.Put(methodQualifier)
 	}
 
 	for i := 0; i < obj.NumEmbeddeds(); i++ {
@@ -329,10 +1311,46 @@ func putInterface(table *meta.Table, fset *parseCtx, obj *types.Interface) (meta
 			return "", err
 		}
 		res.Embeddeds = append(res.Embeddeds, typeQualifier)
-		builder.Put(typeQualifier)
 	}
 
-	q := builder.Finish()
+	// like types.TypeString, the notation lists the explicit methods by name and signature followed by the embedded
+	// types, e.g. interface{Close() (error); io.Reader}. The methods of the underlying interface of a named type are
+	// declared by that named type, so they are written as their declaration id, e.g. interface{io.Closer.Close}.
+	var elems []string
+	for i := 0; i < obj.NumExplicitMethods(); i++ {
+		method := obj.ExplicitMethod(i)
+		sig := method.Type().(*types.Signature)
+		if !types.Identical(sig.Recv().Type(), obj) {
+			mQual, err := putFunc(table, fset, method)
+			if err != nil {
+				return "", err
+			}
+
+			elems = append(elems, string(mQual))
+			continue
+		}
+
+		_, _, notation, err := putParams(table, fset, sig)
+		if err != nil {
+			return "", err
+		}
+
+		elems = append(elems, method.Name()+notation)
+	}
+
+	for _, embedded := range res.Embeddeds {
+		elems = append(elems, string(embedded))
+	}
+
+	q := fset.declId("interface{" + strings.Join(elems, "; ") + "}")
+
+	// the methods of an anonymous interface are declared by the interface itself
+	for i := 0; i < obj.NumMethods(); i++ {
+		recv := obj.Method(i).Type().(*types.Signature).Recv()
+		if named := table.Declarations[res.AllMethods[i]].Named; named != nil && types.Identical(recv.Type(), obj) {
+			named.Receiver = q
+		}
+	}
 
 	table.PutDeclaration(q, meta.Type{
 		Interface: res,
@@ -351,7 +1369,7 @@ func putSlice(table *meta.Table, fset *parseCtx, obj *types.Slice) (meta.DeclId,
 		DeclId: tQual,
 	}
 
-	q := meta.NewDeclId().Put("slice", res.DeclId).Finish()
+	q := fset.declId("[]" + string(res.DeclId))
 
 	table.PutDeclaration(q, meta.Type{
 		Slice: res,
@@ -399,13 +1417,30 @@ func putBasic(table *meta.Table, fset *parseCtx, obj *types.Basic) (meta.DeclId,
 		myKind = meta.String
 	case types.UnsafePointer:
 		myKind = meta.UnsafePointer
+	case types.UntypedBool:
+		myKind = meta.UntypedBool
+	case types.UntypedInt:
+		myKind = meta.UntypedInt
+	case types.UntypedRune:
+		myKind = meta.UntypedRune
+	case types.UntypedFloat:
+		myKind = meta.UntypedFloat
+	case types.UntypedComplex:
+		myKind = meta.UntypedComplex
+	case types.UntypedString:
+		myKind = meta.UntypedString
+	case types.UntypedNil:
+		myKind = meta.UntypedNil
+	case types.Invalid:
+		// the type checker has already reported the reason, e.g. an undefined type
+		return "", fmt.Errorf("invalid type")
 	default:
 		panic("not implemented: basic type " + strconv.Itoa(int(obj.Kind())))
 
 	}
 
 	kind := myKind
-	did := meta.NewDeclId().Put("basic", kind.String()).Finish()
+	did := fset.declId(kind.String())
 	if table.HasDeclaration(did) {
 		return did, nil
 	}
@@ -429,7 +1464,7 @@ func putArray(table *meta.Table, fset *parseCtx, obj *types.Array) (meta.DeclId,
 		DeclId: tQual,
 	}
 
-	q := meta.NewDeclId().Put("array", int(obj.Len()), res.DeclId).Finish()
+	q := fset.declId("[" + strconv.FormatInt(obj.Len(), 10) + "]" + string(res.DeclId))
 
 	table.PutDeclaration(q, meta.Type{
 		Array: res,
@@ -446,7 +1481,7 @@ func putPointer(table *meta.Table, fset *parseCtx, pointer *types.Pointer) (meta
 
 	res := &meta.Pointer{Base: baseQual}
 
-	q := meta.NewDeclId().Put("ptr", res.Base).Finish()
+	q := fset.declId("*" + string(res.Base))
 	table.PutDeclaration(q, meta.Type{
 		Pointer: res,
 	})
@@ -455,8 +1490,7 @@ func putPointer(table *meta.Table, fset *parseCtx, pointer *types.Pointer) (meta
 }
 
 func putStruct(table *meta.Table, fset *parseCtx, strct *types.Struct) (meta.DeclId, error) {
-	builder := meta.NewDeclId()
-	builder.Put("struct")
+	var fields []string
 	res := &meta.Struct{}
 	for i := 0; i < strct.NumFields(); i++ {
 		// TODO what about the tags? this should be a feature of the named declaration?
@@ -468,14 +1502,25 @@ func putStruct(table *meta.Table, fset *parseCtx, strct *types.Struct) (meta.Dec
 			return "", err
 		}
 		p := meta.Param{
-			Name:   f.Name(),
-			DeclId: pQual,
+			Name:     f.Name(),
+			DeclId:   pQual,
+			Embedded: f.Embedded(),
 		}
 		res.Fields = append(res.Fields, p)
-		builder.Put(p.Name, p.DeclId, tag)
+
+		field := p.Name + " " + string(p.DeclId)
+		if f.Embedded() {
+			field = string(p.DeclId)
+		}
+
+		if tag != "" {
+			field += " " + strconv.Quote(tag)
+		}
+
+		fields = append(fields, field)
 	}
 
-	q := builder.Finish()
+	q := fset.declId("struct{" + strings.Join(fields, "; ") + "}")
 
 	table.PutDeclaration(q, meta.Type{
 		Struct: res,
@@ -484,89 +1529,177 @@ func putStruct(table *meta.Table, fset *parseCtx, strct *types.Struct) (meta.Dec
 	return q, nil
 }
 
-// findNode picks the ast node by matching the exact position or returns nil
-func findNode(ctx *parseCtx, pos token.Pos) (n ast.Node) {
-	for _, f := range ctx.files {
-		ast.Inspect(f, func(node ast.Node) bool {
-			if node == nil {
-				return true
-			}
+func putAlias(table *meta.Table, fset *parseCtx, obj *types.Alias) (_ meta.DeclId, err error) {
+	alias := obj.Obj()
+	pos := fset.fset.Position(alias.Pos())
+	pkgImportPath := ""
+	pkgName := ""
 
-			if node.Pos() == pos {
-				n = node
-				return false
-			}
-			return true
-		})
+	if alias.Pkg() != nil {
+		pkgImportPath = alias.Pkg().Path()
+		pkgName = alias.Pkg().Name()
 	}
 
-	return
+	if obj.Origin() != obj {
+		return putInstance(table, fset, obj.Origin(), obj.TypeArgs())
+	}
+
+	qualifier := fset.pkgDeclId(alias.Pkg(), alias.Name())
+
+	if fset.declared(table, qualifier) {
+		return qualifier, nil
+	}
+
+	// a recursive alias, e.g. type A []*B; type B = A
+	fset.pending[qualifier] = true
+	defer fset.finish(table, qualifier, &err)
+
+	loc := meta.NewLocation(pos.Filename, pos.Line, pos.Column)
+
+	s := fset.index.doc(alias.Pos())
+	fset.registerTypeParams(qualifier, obj.TypeParams())
+	typeParams, err := putTypeParams(table, fset, obj.TypeParams())
+	if err != nil {
+		return "", err
+	}
+
+	// Rhs is the declared target, which may be itself an alias
+	target, err := putType(table, fset, obj.Rhs())
+	if err != nil {
+		return "", err
+	}
+
+	table.PutPackageDeclaration(pkgImportPath, pkgName, qualifier, meta.Type{
+		Alias: &meta.Alias{
+			Location:        loc,
+			Doc:             s,
+			Annotations:     fset.annotations(loc, qualifier, s),
+			Name:            alias.Name(),
+			Target:          target,
+			TypeParams:      typeParams,
+			BuildConstraint: fset.index.buildConstraint(alias.Pos()),
+		},
+	})
+
+	return qualifier, nil
 }
 
-// findTypeComment searches through the ast.TypeSpec and picks the comment.
-// See also https://github.com/golang/go/issues/27477#issuecomment-418563062 for details.
-func findTypeComment(ctx *parseCtx, pos token.Pos) string {
-	var lastGen *ast.GenDecl
-	var lastFunc *ast.FuncDecl
-	isTypeSpec := false
-	isFuncSpec := false
-	actualDoc := ""
-	done := false
-
-	for _, f := range ctx.files {
-		ast.Inspect(f, func(node ast.Node) bool {
-			if node == nil {
-				return true
-			}
-
-			// this closure is invoked, even if the last TypeSpec check returns false
-			if genDecl, ok := node.(*ast.GenDecl); ok && !done {
-				lastGen = genDecl
-				return true
-			}
-
-			if x, ok := node.(*ast.FuncDecl); ok && !done {
-				lastFunc = x
-				return true
-			}
-
-			if node.Pos() == pos && !done {
-				done = true
-				switch t := node.(type) {
-				case *ast.TypeSpec:
-					isTypeSpec = true
-					actualDoc = t.Doc.Text()
-				case *ast.Field:
-					actualDoc = t.Doc.Text()
-				case *ast.Ident:
-					isFuncSpec = true //TODO wrong assumption?
-				default:
-					panic(reflect.TypeOf(t))
-				}
-				return false
-			}
-			return true
-		})
+// putTypeParams inserts the declared type parameters, if any.
+func putTypeParams(table *meta.Table, fset *parseCtx, list *types.TypeParamList) ([]meta.DeclId, error) {
+	var res []meta.DeclId
+	for i := 0; i < list.Len(); i++ {
+		tQual, err := putTypeParam(table, fset, list.At(i))
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, tQual)
+	}
+
+	return res, nil
+}
+
+func putTypeParam(table *meta.Table, fset *parseCtx, obj *types.TypeParam) (_ meta.DeclId, err error) {
+	qualifier, ok := fset.typeParams[obj]
+	if !ok {
+		// usually the declaring type or function has been registered, otherwise the position makes it unique
+		pkgImportPath := ""
+		if obj.Obj().Pkg() != nil {
+			pkgImportPath = obj.Obj().Pkg().Path()
+		}
+
+		pos := fset.fset.Position(obj.Obj().Pos())
+		loc := meta.NewLocation(filepath.Base(pos.Filename), pos.Line, pos.Column)
+		qualifier = fset.declId(pkgImportPath + "#" + obj.Obj().Name() + "@" + string(loc))
+	}
+
+	if fset.declared(table, qualifier) {
+		return qualifier, nil
+	}
+
+	// a recursive constraint, e.g. [T interface{ Less(T) bool }]
+	fset.pending[qualifier] = true
+	defer fset.finish(table, qualifier, &err)
+
+	cQual, err := putType(table, fset, obj.Constraint())
+	if err != nil {
+		return "", err
 	}
-	if !done {
-		return ""
+
+	table.PutDeclaration(qualifier, meta.Type{
+		TypeParam: &meta.TypeParam{
+			Name:       obj.Obj().Name(),
+			Index:      obj.Index(),
+			Constraint: cQual,
+		},
+	})
+
+	return qualifier, nil
+}
+
+func putUnion(table *meta.Table, fset *parseCtx, obj *types.Union) (meta.DeclId, error) {
+	res := &meta.Union{}
+
+	var terms []string
+	for i := 0; i < obj.Len(); i++ {
+		term := obj.Term(i)
+		tQual, err := putType(table, fset, term.Type())
+		if err != nil {
+			return "", err
+		}
+
+		res.Terms = append(res.Terms, meta.Term{
+			Tilde:  term.Tilde(),
+			DeclId: tQual,
+		})
+
+		if term.Tilde() {
+			terms = append(terms, "~"+string(tQual))
+		} else {
+			terms = append(terms, string(tQual))
+		}
 	}
 
-	s := ""
-	if isTypeSpec && lastGen != nil {
-		s += lastGen.Doc.Text()
+	q := fset.declId(strings.Join(terms, " | "))
+
+	table.PutDeclaration(q, meta.Type{
+		Union: res,
+	})
+
+	return q, nil
+}
+
+// putInstance inserts an instantiated generic type, which refers to its generic origin.
+func putInstance(table *meta.Table, fset *parseCtx, origin types.Type, typeArgs *types.TypeList) (meta.DeclId, error) {
+	oQual, err := putType(table, fset, origin)
+	if err != nil {
+		return "", err
 	}
 
-	if isFuncSpec && lastFunc != nil {
-		s += lastFunc.Doc.Text()
+	res := &meta.Instance{Origin: oQual}
+
+	for i := 0; i < typeArgs.Len(); i++ {
+		tQual, err := putType(table, fset, typeArgs.At(i))
+		if err != nil {
+			return "", err
+		}
+
+		res.TypeArgs = append(res.TypeArgs, tQual)
 	}
 
-	s += actualDoc
+	q := fset.declId(string(oQual) + "[" + joinIds(res.TypeArgs, ", ") + "]")
+
+	table.PutDeclaration(q, meta.Type{
+		Instance: res,
+	})
 
-	return strings.TrimSpace(s)
+	return q, nil
 }
 
-func putNamedType(table *meta.Table, fset *parseCtx, obj *types.Named) (meta.DeclId, error) {
+func putNamedType(table *meta.Table, fset *parseCtx, obj *types.Named) (_ meta.DeclId, err error) {
+	if obj.Origin() != obj {
+		return putInstance(table, fset, obj.Origin(), obj.TypeArgs())
+	}
 
 	named := obj.Obj()
 	pos := fset.fset.Position(named.Pos())
@@ -578,21 +1711,23 @@ func putNamedType(table *meta.Table, fset *parseCtx, obj *types.Named) (meta.Dec
 		pkgName = named.Pkg().Name()
 	}
 
-	qualifier := meta.NewDeclId().Put("func", pkgImportPath, pkgName, named.Name()).Finish()
+	qualifier := fset.pkgDeclId(named.Pkg(), named.Name())
 
-	if table.HasDeclaration(qualifier) {
+	if fset.declared(table, qualifier) {
 		return qualifier, nil
 	}
 
-	// fill in some dummy type, to avoid endless recursion
-	table.PutDeclaration(qualifier, meta.Type{})
+	// a recursive type, e.g. type Node struct{ Next *Node }
+	fset.pending[qualifier] = true
+	defer fset.finish(table, qualifier, &err)
 
 	loc := meta.NewLocation(pos.Filename, pos.Line, pos.Column)
 
-	s := findTypeComment(fset, named.Pos())
-	annotations, err := annotation.Parse(s)
+	s := fset.index.doc(named.Pos())
+	fset.registerTypeParams(qualifier, obj.TypeParams())
+	typeParams, err := putTypeParams(table, fset, obj.TypeParams())
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", loc, err)
+		return "", err
 	}
 
 	myUnderlyingType, err := putType(table, fset, named.Type().Underlying())
@@ -600,27 +1735,34 @@ func putNamedType(table *meta.Table, fset *parseCtx, obj *types.Named) (meta.Dec
 		return "", err
 	}
 
+	declaredType, err := putDeclaredType(table, fset, named)
+	if err != nil {
+		return "", err
+	}
+
 	res := &meta.Named{
-		Location:    loc,
-		Doc:         s,
-		Annotations: wrapAnnotations(loc, annotations),
-		Underlying:  myUnderlyingType,
-		Name:        named.Name(),
+		Location:        loc,
+		Doc:             s,
+		Annotations:     fset.annotations(loc, qualifier, s),
+		Underlying:      myUnderlyingType,
+		Declared:        declaredType,
+		Name:            named.Name(),
+		TypeParams:      typeParams,
+		BuildConstraint: fset.index.buildConstraint(named.Pos()),
 	}
 
 	// this is ugly, but the information has been lost. We define, that the first underlying type of a
 	// struct is always specific and requires another underlying type without tags, docs etc.
 	// TODO currently this only works, if the tags are different
-	node := findNode(fset, named.Pos())
-	if typeSpec, ok := node.(*ast.TypeSpec); ok {
+	if typeSpec := fset.index.typeSpec(named.Pos()); typeSpec != nil {
 		if structType, ok := typeSpec.Type.(*ast.StructType); ok {
 			if structType.Fields != nil {
 				// enrich the field param information
 				strct := table.Declarations[myUnderlyingType].Struct
 				for _, field := range structType.Fields.List {
-					for _, fieldName := range field.Names {
+					for _, fieldName := range astFieldNames(named.Type().Underlying().(*types.Struct), field) {
 						for i, strctField := range strct.Fields {
-							if strctField.Name == fieldName.Name {
+							if strctField.Name == fieldName {
 								pos := fset.fset.Position(field.Pos())
 								loc := meta.NewLocation(pos.Filename, pos.Line, pos.Column)
 								strctField.Pos = &loc
@@ -630,11 +1772,7 @@ func putNamedType(table *meta.Table, fset *parseCtx, obj *types.Named) (meta.Dec
 								}
 
 								strctField.Doc = field.Doc.Text()
-								fieldAnnos, err := annotation.Parse(strctField.Doc)
-								if err != nil {
-									return "", fmt.Errorf("%s: %w", loc, err)
-								}
-								strctField.Annotations = wrapAnnotations(loc, fieldAnnos)
+								strctField.Annotations = fset.annotations(loc, qualifier, strctField.Doc)
 								// reassign loop value
 								strct.Fields[i] = strctField
 							}
@@ -656,11 +1794,233 @@ func putNamedType(table *meta.Table, fset *parseCtx, obj *types.Named) (meta.Dec
 		res.Methods = append(res.Methods, mQual)
 	}
 
+	if _, ok := obj.Underlying().(*types.Struct); ok {
+		res.PromotedFields, res.PromotedMethods, err = putPromoted(table, fset, obj)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// the method set of an interface is already defined by its AllMethods
+	if !types.IsInterface(obj) {
+		res.ValueMethodSet, err = putMethodSet(table, fset, obj)
+		if err != nil {
+			return "", err
+		}
+
+		res.PointerMethodSet, err = putMethodSet(table, fset, types.NewPointer(obj))
+		if err != nil {
+			return "", err
+		}
+	}
+
 	table.PutNamedDeclaration(pkgImportPath, pkgName, qualifier, res)
 
 	return qualifier, nil
 }
 
+// putDeclaredType inserts the type of the RHS of the type declaration, which is only known from the syntax of the
+// package, which is put into the table. Otherwise, e.g. for the export data of a dependency, it returns the empty
+// DeclId.
+func putDeclaredType(table *meta.Table, fset *parseCtx, named *types.TypeName) (meta.DeclId, error) {
+	typeSpec := fset.index.typeSpec(named.Pos())
+	if typeSpec == nil || fset.info == nil {
+		return "", nil
+	}
+
+	declared := fset.info.TypeOf(typeSpec.Type)
+	if declared == nil {
+		return "", nil
+	}
+
+	return putType(table, fset, declared)
+}
+
+// putConstructors attaches the package level functions to the named types, which they construct. A constructor
+// returns T or *T, optionally followed by an error, and is either named like NewT or annotated with @Constructor.
+// Without the annotation, the constructed type must be declared in the same package.
+func putConstructors(table *meta.Table) {
+	importTable := table.CreateImportTable()
+	for _, id := range table.DeclIds() {
+		fun := table.Declarations[id].Named
+		if fun == nil || !fun.Func || fun.Receiver != "" {
+			continue
+		}
+
+		sig := table.Declarations[fun.Underlying].Signature
+		if len(sig.Results) == 0 || len(sig.Results) > 2 {
+			continue
+		}
+
+		if len(sig.Results) == 2 {
+			if errType := table.Declarations[sig.Results[1].DeclId].Named; errType == nil || errType.Name != "error" {
+				continue
+			}
+		}
+
+		typeId := sig.Results[0].DeclId
+		if ptr := table.Declarations[typeId].Pointer; ptr != nil {
+			typeId = ptr.Base
+		}
+
+		named := table.Declarations[typeId].Named
+		if named == nil || named.Func {
+			continue
+		}
+
+		annotated := false
+		for _, a := range fun.Annotations {
+			if a.Name == "Constructor" {
+				annotated = true
+			}
+		}
+
+		if !annotated && (!strings.HasPrefix(fun.Name, "New") || importTable[id] != importTable[typeId]) {
+			continue
+		}
+
+		named.Constructors = append(named.Constructors, id)
+	}
+}
+
+// astFieldNames returns the declared names of the field. An embedded field has no names in the ast, so its
+// implicit name is looked up from the type checked struct.
+func astFieldNames(strct *types.Struct, field *ast.Field) []string {
+	var res []string
+	for _, name := range field.Names {
+		res = append(res, name.Name)
+	}
+
+	if len(field.Names) == 0 {
+		for i := 0; i < strct.NumFields(); i++ {
+			f := strct.Field(i)
+			if f.Embedded() && f.Pos() >= field.Type.Pos() && f.Pos() < field.Type.End() {
+				res = append(res, f.Name())
+			}
+		}
+	}
+
+	return res
+}
+
+// putPromoted collects the fields and methods which are promoted through embedded fields of the given struct
+// type. The method set of the pointer type is used, so that methods with pointer receivers are also included.
+func putPromoted(table *meta.Table, fset *parseCtx, obj *types.Named) ([]meta.Promoted, []meta.Promoted, error) {
+	var fields, methods []meta.Promoted
+
+	// collect the names of all nested fields, the lookup decides about shadowing and ambiguity
+	names := map[string]bool{}
+	visited := map[types.Type]bool{}
+	var collect func(strct *types.Struct, depth int)
+	collect = func(strct *types.Struct, depth int) {
+		for i := 0; i < strct.NumFields(); i++ {
+			f := strct.Field(i)
+			if depth > 0 {
+				names[f.Name()] = true
+			}
+
+			if !f.Embedded() {
+				continue
+			}
+
+			typ := derefType(f.Type())
+			if visited[typ] {
+				continue
+			}
+			visited[typ] = true
+
+			if embedded, ok := typ.Underlying().(*types.Struct); ok {
+				collect(embedded, depth+1)
+			}
+		}
+	}
+	collect(obj.Underlying().(*types.Struct), 0)
+
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	for _, name := range sortedNames {
+		member, index, _ := types.LookupFieldOrMethod(obj, true, obj.Obj().Pkg(), name)
+		field, ok := member.(*types.Var)
+		if !ok || len(index) < 2 {
+			continue
+		}
+
+		id, err := putType(table, fset, field.Type())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fields = append(fields, meta.Promoted{
+			Name:   name,
+			Path:   embeddedPath(obj, index),
+			DeclId: id,
+		})
+	}
+
+	mset := types.NewMethodSet(types.NewPointer(obj))
+	for i := 0; i < mset.Len(); i++ {
+		sel := mset.At(i)
+		if len(sel.Index()) < 2 {
+			continue
+		}
+
+		id, err := putFunc(table, fset, sel.Obj().(*types.Func))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		methods = append(methods, meta.Promoted{
+			Name:   sel.Obj().Name(),
+			Path:   embeddedPath(obj, sel.Index()),
+			DeclId: id,
+		})
+	}
+
+	return fields, methods, nil
+}
+
+// putMethodSet returns the declared and promoted methods which can be called on a value of the given type,
+// sorted by name.
+func putMethodSet(table *meta.Table, fset *parseCtx, typ types.Type) ([]meta.DeclId, error) {
+	var res []meta.DeclId
+	mset := types.NewMethodSet(typ)
+	for i := 0; i < mset.Len(); i++ {
+		id, err := putFunc(table, fset, mset.At(i).Obj().(*types.Func))
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, id)
+	}
+
+	return res, nil
+}
+
+// embeddedPath resolves the names of the embedded fields of a selection index, without the selected member.
+func embeddedPath(typ types.Type, index []int) []string {
+	var res []string
+	for _, i := range index[:len(index)-1] {
+		f := derefType(typ).Underlying().(*types.Struct).Field(i)
+		res = append(res, f.Name())
+		typ = f.Type()
+	}
+
+	return res
+}
+
+// derefType returns the element type of a pointer or the type itself.
+func derefType(typ types.Type) types.Type {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+
+	return typ
+}
+
 func wrapAnnotations(loc meta.Location, list []annotation.Annotation) []meta.Annotation {
 	res := make([]meta.Annotation, 0, len(list))
 	for _, a := range list {
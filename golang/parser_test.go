@@ -15,15 +15,56 @@
 package golang
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/golangee/reflectplus/meta"
 	"github.com/golangee/src"
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
+var testProject struct {
+	once sync.Once
+	prj  *Project
+	err  error
+}
+
+// loadTestProject parses the test module only once and shares the result between all tests.
+func loadTestProject(t *testing.T) *Project {
+	testProject.once.Do(func() {
+		testProject.prj, testProject.err = NewProject(Options{
+			Dir:      "../internal/test",
+			Patterns: []string{"github.com/golangee/..."},
+		})
+	})
+
+	if testProject.err != nil {
+		t.Fatal(testProject.err)
+	}
+
+	return testProject.prj
+}
+
 func TestNewProject(t *testing.T) {
 	opts := Options{
-		Dir:      "/Users/tschinke/git/github.com/golangee/reflectplus/internal/test",
+		Dir:      "../internal/test",
 		Patterns: []string{"github.com/golangee/..."},
 	}
 	//mods, err := NewProject(opts, "/Users/tschinke/git/github.com/worldiety/mercurius/", nil)
@@ -75,6 +116,11 @@ func TestNewProject(t *testing.T) {
 
 	mod.ForEachInterface(func(pkg *meta.Package, id meta.DeclId, named *meta.Named, iface *meta.Interface) {
 		fmt.Println("iface ", pkg.Path, "=>", named.Name)
+		if len(named.TypeParams) > 0 {
+			// generic interfaces can only be implemented as an instantiation, see TestGenerics
+			return
+		}
+
 		impl, err := mod.Implement(id, func(ctx MethodContext) {
 			fmt.Println("   ?>", ctx.MethodAnnotations)
 			if len(ctx.Method.Results()) > 0 {
@@ -113,3 +159,1497 @@ func TestNewProject(t *testing.T) {
 	)
 
 }
+
+func TestProject_ForEachFunc(t *testing.T) {
+	prj := loadTestProject(t)
+
+	funcs := map[string]*meta.Named{}
+	prj.ForEachFunc(func(pkg *meta.Package, id meta.DeclId, named *meta.Named, sig *meta.Signature) {
+		funcs[pkg.Path+"."+named.Name] = named
+	})
+
+	expected := map[string]string{
+		"github.com/golangee/reflectplus/internal/test/internal/stuff.NewMyStruct":    "NewMyStruct is a constructor",
+		"github.com/golangee/reflectplus/internal/test/internal/stuff.SomePublicFunc": "",
+		"github.com/golangee/reflectplus/internal/test/internal/app.CrazyFunc":        "CrazyFunc is like this\n@Command(\"crazy\")",
+	}
+
+	for name, doc := range expected {
+		named, ok := funcs[name]
+		if !ok {
+			t.Fatalf("expected func %s", name)
+		}
+
+		if named.Doc != doc {
+			t.Fatalf("%s: expected doc '%s' but got '%s'", name, doc, named.Doc)
+		}
+	}
+
+	crazy := funcs["github.com/golangee/reflectplus/internal/test/internal/app.CrazyFunc"]
+	if len(crazy.Annotations) != 1 || crazy.Annotations[0].Name != "Command" {
+		t.Fatalf("expected @Command annotation but got %v", crazy.Annotations)
+	}
+
+	for _, name := range []string{
+		"github.com/golangee/reflectplus/internal/test/internal/stuff.MyFunc",
+		"github.com/golangee/reflectplus/internal/test/internal/stuff.SomeMethod0",
+		"github.com/golangee/reflectplus/internal/test/cmd/prog0.main",
+	} {
+		if _, ok := funcs[name]; ok {
+			t.Fatalf("%s must not be a package level function", name)
+		}
+	}
+}
+
+// findDecl returns the first declaration of the given package which has the name.
+func findDecl(prj *Project, pkgPath, name string) (meta.DeclId, meta.Type) {
+	pid, ok := prj.table.PackageByImportPath(pkgPath)
+	if !ok {
+		return "", meta.Type{}
+	}
+
+	for _, id := range prj.table.Packages[pid].Declarations {
+		decl := prj.table.Declarations[id]
+		if (decl.Named != nil && decl.Named.Name == name) || (decl.Const != nil && decl.Const.Name == name) ||
+			(decl.Alias != nil && decl.Alias.Name == name) {
+			return id, decl
+		}
+	}
+
+	return "", meta.Type{}
+}
+
+const stuffPkg = "github.com/golangee/reflectplus/internal/test/internal/stuff"
+
+func TestProject_EnumValues(t *testing.T) {
+	prj := loadTestProject(t)
+
+	statusId, _ := findDecl(prj, stuffPkg, "Status")
+	if statusId == "" {
+		t.Fatal("expected type Status")
+	}
+
+	values := prj.EnumValues(statusId)
+	if len(values) != 3 {
+		t.Fatalf("expected 3 enum values but got %d", len(values))
+	}
+
+	for i, name := range []string{"Unknown", "Running", "Stopped"} {
+		c := values[i]
+		if c.Name != name || c.Value != strconv.Itoa(i) || c.Iota != i || c.Kind != meta.ConstInt {
+			t.Fatalf("unexpected enum value %+v", c)
+		}
+
+		if c.Block != values[0].Block {
+			t.Fatalf("expected all values in the same block")
+		}
+	}
+
+	if values[0].Doc != "Status values\nUnknown is the default\n@Default" || len(values[0].Annotations) != 1 {
+		t.Fatalf("unexpected doc '%s' or annotations %v", values[0].Doc, values[0].Annotations)
+	}
+}
+
+func TestProject_ForEachConst(t *testing.T) {
+	prj := loadTestProject(t)
+
+	consts := map[string]*meta.Const{}
+	prj.ForEachConst(func(pkg *meta.Package, id meta.DeclId, c *meta.Const) {
+		if pkg.Path == stuffPkg {
+			consts[c.Name] = c
+		}
+	})
+
+	if _, ok := consts["notExported"]; ok {
+		t.Fatal("private constants must be ignored")
+	}
+
+	type test struct {
+		Name  string
+		Kind  meta.ConstKind
+		Basic meta.BasicKind
+		Value string
+	}
+
+	sources := map[string]string{
+		"Greeting": `"hello"`,
+		"Pi":       "3.14",
+		"Third":    "1.0 / 3",
+		"Two":      "2.0",
+		"Avogadro": "6.02214076e23",
+		"Tiny":     "1e-300",
+		"Huge":     "1e5000",
+		"Wave":     "2.5i",
+		"Big":      "1 << 100",
+		"Enabled":  "true",
+	}
+
+	for _, r := range []test{
+		{"Greeting", meta.ConstString, meta.UntypedString, `"hello"`},
+		{"Pi", meta.ConstFloat, meta.UntypedFloat, "3.14"},
+		{"Third", meta.ConstFloat, meta.UntypedFloat, "1.0/3"},
+		{"Two", meta.ConstFloat, meta.UntypedFloat, "2.0"},
+		{"Avogadro", meta.ConstFloat, meta.UntypedFloat, "6.02214076e23"},
+		{"Tiny", meta.ConstFloat, meta.UntypedFloat, "1e-300"},
+		{"Huge", meta.ConstFloat, meta.UntypedFloat, "1e+5000"},
+		{"Wave", meta.ConstComplex, meta.UntypedComplex, "complex(0.0, 2.5)"},
+		{"Big", meta.ConstInt, meta.UntypedInt, "1267650600228229401496703205376"},
+		{"Enabled", meta.ConstBool, meta.UntypedBool, "true"},
+	} {
+		c, ok := consts[r.Name]
+		if !ok {
+			t.Fatalf("expected constant %s", r.Name)
+		}
+
+		basic := prj.table.Declarations[c.DeclId].Basic
+		if c.Kind != r.Kind || c.Value != r.Value || basic == nil || basic.Kind != r.Basic {
+			t.Fatalf("%s: unexpected constant %+v", r.Name, c)
+		}
+
+		// the value must evaluate to the declared untyped constant
+		declared, _ := types.Eval(token.NewFileSet(), nil, token.NoPos, sources[r.Name])
+		tv, err := types.Eval(token.NewFileSet(), nil, token.NoPos, c.Value)
+		if err != nil || tv.Type.String() != string(r.Basic) || !constant.Compare(tv.Value, token.EQL, declared.Value) {
+			t.Fatalf("%s: invalid value %s: %v", r.Name, c.Value, err)
+		}
+	}
+}
+
+func TestProject_ForEachVar(t *testing.T) {
+	prj := loadTestProject(t)
+
+	vars := map[string]*meta.Var{}
+	prj.ForEachVar(func(pkg *meta.Package, id meta.DeclId, v *meta.Var) {
+		if pkg.Path == stuffPkg {
+			vars[v.Name] = v
+		}
+	})
+
+	if len(vars) != 3 {
+		t.Fatalf("expected exactly 3 exported vars but got %v", vars)
+	}
+
+	errNotFound := vars["ErrNotFound"]
+	if errNotFound == nil || len(errNotFound.Annotations) != 1 || errNotFound.Annotations[0].Values["code"] != 404.0 {
+		t.Fatalf("unexpected var %+v", errNotFound)
+	}
+
+	if errType := prj.table.Declarations[errNotFound.DeclId].Named; errType == nil || errType.Name != "error" {
+		t.Fatalf("expected error type but got %+v", prj.table.Declarations[errNotFound.DeclId])
+	}
+
+	structId, _ := findDecl(prj, stuffPkg, "MyStruct")
+	if def := vars["DefaultStruct"]; def == nil || def.DeclId != structId || def.Doc != "DefaultStruct is used if nothing else is configured" {
+		t.Fatalf("unexpected var %+v", def)
+	}
+
+	if reg := vars["Registry"]; reg == nil || prj.table.Declarations[reg.DeclId].Map == nil {
+		t.Fatalf("unexpected var %+v", reg)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	prj := loadTestProject(t)
+
+	aliasId, alias := findDecl(prj, stuffPkg, "MyAlias")
+	if alias.Alias == nil {
+		t.Fatalf("expected alias but got %+v", alias)
+	}
+
+	targetId, _ := findDecl(prj, stuffPkg, "MyString")
+	if alias.Alias.Target != targetId || alias.Alias.Doc != "MyAlias doc" {
+		t.Fatalf("unexpected alias %+v", alias.Alias)
+	}
+
+	_, myStruct := findDecl(prj, stuffPkg, "MyStruct")
+	for _, field := range prj.table.Declarations[myStruct.Named.Underlying].Struct.Fields {
+		if field.Name == "secret" && field.DeclId != aliasId {
+			t.Fatalf("expected field to refer to the alias")
+		}
+	}
+
+	code := src.NewFile("test").AddTypes(src.NewStruct("S").AddFields(src.NewField("F", prj.TypeDecl(aliasId)))).String()
+	if !strings.Contains(code, "F stuff.MyAlias") {
+		t.Fatalf("expected alias name in generated code:\n%s", code)
+	}
+
+	// the universe scope has no package path
+	_, repo := findDecl(prj, stuffPkg, "Repository")
+	anyId := prj.table.Declarations[repo.Named.TypeParams[0]].TypeParam.Constraint
+	var errorId meta.DeclId
+	prj.ForEachVar(func(pkg *meta.Package, id meta.DeclId, v *meta.Var) {
+		if v.Name == "ErrNotFound" {
+			errorId = v.DeclId
+		}
+	})
+
+	for id, name := range map[meta.DeclId]string{anyId: "any", errorId: "error"} {
+		if q := reflect.ValueOf(prj.TypeDecl(id)).Elem().FieldByName("qualifier").String(); q != name {
+			t.Fatalf("expected the qualifier %s but got %s", name, q)
+		}
+	}
+
+	// recursive declaration through an alias
+	aId, _ := findDecl(prj, stuffPkg, "A")
+	_, b := findDecl(prj, stuffPkg, "B")
+	if b.Alias == nil || b.Alias.Target != aId {
+		t.Fatalf("unexpected alias %+v", b)
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	prj := loadTestProject(t)
+
+	repoId, repo := findDecl(prj, stuffPkg, "Repository")
+	if len(repo.Named.TypeParams) != 1 {
+		t.Fatalf("expected a type parameter but got %+v", repo.Named)
+	}
+
+	typeParam := prj.table.Declarations[repo.Named.TypeParams[0]].TypeParam
+	if typeParam == nil || typeParam.Name != "T" || typeParam.Index != 0 {
+		t.Fatalf("unexpected type parameter %+v", typeParam)
+	}
+
+	if constraint := prj.table.Declarations[typeParam.Constraint].Alias; constraint == nil || constraint.Name != "any" {
+		t.Fatalf("expected any constraint but got %+v", prj.table.Declarations[typeParam.Constraint])
+	}
+
+	if _, err := prj.Implement(repoId, func(ctx MethodContext) {}); err == nil {
+		t.Fatal("expected error for uninstantiated generic interface")
+	}
+
+	// type set union
+	_, number := findDecl(prj, stuffPkg, "Number")
+	iface := prj.table.Declarations[number.Named.Underlying].Interface
+	if len(iface.Embeddeds) != 1 {
+		t.Fatalf("expected a union but got %+v", iface)
+	}
+
+	union := prj.table.Declarations[iface.Embeddeds[0]].Union
+	if union == nil || len(union.Terms) != 3 || !union.Terms[0].Tilde || union.Terms[2].Tilde {
+		t.Fatalf("unexpected union %+v", union)
+	}
+
+	// instantiated interface
+	_, myStructRepo := findDecl(prj, stuffPkg, "MyStructRepository")
+	myStructId, _ := findDecl(prj, stuffPkg, "MyStruct")
+	inst := prj.table.Declarations[myStructRepo.Alias.Target].Instance
+	if inst == nil || inst.Origin != repoId || len(inst.TypeArgs) != 1 || inst.TypeArgs[0] != myStructId {
+		t.Fatalf("unexpected instance %+v", inst)
+	}
+
+	impl, err := prj.Implement(myStructRepo.Alias.Target, func(ctx MethodContext) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := src.NewFile("test").AddTypes(impl).String()
+	if !strings.Contains(code, "FindAll() ([]stuff.MyStruct, error)") || !strings.Contains(code, "Save(entity stuff.MyStruct) error") {
+		t.Fatalf("expected substituted type parameters:\n%s", code)
+	}
+
+	// generic struct referring to its own instance
+	pageId, page := findDecl(prj, stuffPkg, "Page")
+	if len(page.Named.TypeParams) != 2 {
+		t.Fatalf("unexpected type params %+v", page.Named)
+	}
+
+	for _, field := range prj.table.Declarations[page.Named.Underlying].Struct.Fields {
+		if field.Name == "Next" {
+			next := prj.table.Declarations[prj.table.Declarations[field.DeclId].Pointer.Base].Instance
+			if next == nil || next.Origin != pageId || next.TypeArgs[0] != page.Named.TypeParams[0] {
+				t.Fatalf("unexpected instance %+v", next)
+			}
+
+			code := src.NewFile("test").AddTypes(src.NewStruct("S").AddFields(src.NewField("F", prj.TypeDecl(field.DeclId)))).String()
+			if !strings.Contains(code, "F *stuff.Page[T, N]") {
+				t.Fatalf("unexpected instance declaration:\n%s", code)
+			}
+		}
+	}
+
+	// generic alias and its instance
+	setId, set := findDecl(prj, stuffPkg, "Set")
+	if len(set.Alias.TypeParams) != 1 || prj.table.Declarations[set.Alias.Target].Map == nil {
+		t.Fatalf("unexpected generic alias %+v", set.Alias)
+	}
+
+	_, stringSet := findDecl(prj, stuffPkg, "StringSet")
+	if inst := prj.table.Declarations[stringSet.Alias.Target].Instance; inst == nil || inst.Origin != setId {
+		t.Fatalf("unexpected alias instance %+v", inst)
+	}
+
+	// recursive constraint
+	_, sortable := findDecl(prj, stuffPkg, "Sortable")
+	if len(sortable.Named.TypeParams) != 1 {
+		t.Fatalf("unexpected type params %+v", sortable.Named)
+	}
+
+	// generic function
+	var sum *meta.Signature
+	prj.ForEachFunc(func(pkg *meta.Package, id meta.DeclId, named *meta.Named, sig *meta.Signature) {
+		if named.Name == "Sum" {
+			sum = sig
+		}
+	})
+
+	if sum == nil || len(sum.TypeParams) != 1 || !sum.Variadic {
+		t.Fatalf("unexpected generic func %+v", sum)
+	}
+}
+
+func TestMethodReceiver(t *testing.T) {
+	prj := loadTestProject(t)
+
+	structId, myStruct := findDecl(prj, stuffPkg, "MyStruct")
+	ifaceId, someIface := findDecl(prj, stuffPkg, "SomeIface")
+
+	structMethod := prj.table.Declarations[myStruct.Named.Methods[0]].Named
+	ifaceMethodId := prj.table.Declarations[someIface.Named.Underlying].Interface.AllMethods[0]
+	ifaceMethod := prj.table.Declarations[ifaceMethodId].Named
+
+	if myStruct.Named.Methods[0] == ifaceMethodId {
+		t.Fatal("methods of different receivers must not collide")
+	}
+
+	if structMethod.Receiver != structId || structMethod.Doc != "SomeMethod0 is implemented by MyStruct\n@Transactional" {
+		t.Fatalf("unexpected struct method %+v", structMethod)
+	}
+
+	if ifaceMethod.Receiver != ifaceId || ifaceMethod.Doc != "SomeMethod0 doc" || len(ifaceMethod.Annotations) != 0 {
+		t.Fatalf("unexpected interface method %+v", ifaceMethod)
+	}
+
+	// embedded interface methods are still declared by the embedded interface
+	_, myIface := findDecl(prj, stuffPkg, "MyInterface")
+	found := false
+	for _, id := range prj.table.Declarations[myIface.Named.Underlying].Interface.AllMethods {
+		if id == ifaceMethodId {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatal("expected embedded method of SomeIface")
+	}
+
+	// methods of generic types belong to the origin
+	repoId, repo := findDecl(prj, stuffPkg, "Repository")
+	for _, id := range prj.table.Declarations[repo.Named.Underlying].Interface.AllMethods {
+		if recv := prj.table.Declarations[id].Named.Receiver; recv != repoId {
+			t.Fatalf("expected generic receiver but got %s", recv)
+		}
+	}
+
+	// methods of anonymous interfaces refer to the interface
+	_, sortable := findDecl(prj, stuffPkg, "Sortable")
+	constraintId := prj.table.Declarations[sortable.Named.TypeParams[0]].TypeParam.Constraint
+	less := prj.table.Declarations[prj.table.Declarations[constraintId].Interface.AllMethods[0]].Named
+	if less.Name != "Less" || less.Receiver != constraintId {
+		t.Fatalf("unexpected anonymous interface method %+v", less)
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	prj := loadTestProject(t)
+
+	entityId, _ := findDecl(prj, stuffPkg, "Entity")
+	_, customer := findDecl(prj, stuffPkg, "Customer")
+	strct := prj.table.Declarations[customer.Named.Underlying].Struct
+
+	var embedded []string
+	for _, f := range strct.Fields {
+		if f.Embedded {
+			embedded = append(embedded, f.Name)
+		}
+	}
+
+	if !reflect.DeepEqual(embedded, []string{"Entity", "Auditable", "SomeIface"}) {
+		t.Fatalf("unexpected embedded fields %v", embedded)
+	}
+
+	if strct.Fields[0].DeclId != entityId || strct.Fields[0].Doc != "Entity provides the identity\n" {
+		t.Fatalf("unexpected embedded field %+v", strct.Fields[0])
+	}
+
+	if strct.Fields[1].Tags == nil {
+		t.Fatalf("expected tags of embedded pointer field %+v", strct.Fields[1])
+	}
+
+	// Kind is shadowed by Customer.Kind
+	var fields []string
+	for _, f := range customer.Named.PromotedFields {
+		fields = append(fields, strings.Join(append(f.Path, f.Name), "."))
+	}
+
+	if !reflect.DeepEqual(fields, []string{"Auditable.CreatedBy", "Entity.ID"}) {
+		t.Fatalf("unexpected promoted fields %v", fields)
+	}
+
+	var methods []string
+	for _, m := range customer.Named.PromotedMethods {
+		methods = append(methods, strings.Join(append(m.Path, m.Name), "."))
+		if prj.table.Declarations[m.DeclId].Named == nil {
+			t.Fatalf("promoted method must refer to its declaration %+v", m)
+		}
+	}
+
+	if !reflect.DeepEqual(methods, []string{"Entity.Identifier", "Entity.SetIdentifier", "SomeIface.SomeMethod0"}) {
+		t.Fatalf("unexpected promoted methods %v", methods)
+	}
+}
+
+func TestMethodSets(t *testing.T) {
+	prj := loadTestProject(t)
+
+	names := func(ids []meta.DeclId) []string {
+		var res []string
+		for _, id := range ids {
+			res = append(res, prj.table.Declarations[id].Named.Name)
+		}
+		return res
+	}
+
+	_, entity := findDecl(prj, stuffPkg, "Entity")
+	for _, id := range entity.Named.Methods {
+		m := prj.table.Declarations[id].Named
+		if m.PointerReceiver != (m.Name == "SetIdentifier") {
+			t.Fatalf("unexpected receiver of %s", m.Name)
+		}
+	}
+
+	if got := names(entity.Named.ValueMethodSet); !reflect.DeepEqual(got, []string{"Identifier"}) {
+		t.Fatalf("unexpected value method set %v", got)
+	}
+
+	if got := names(entity.Named.PointerMethodSet); !reflect.DeepEqual(got, []string{"Identifier", "SetIdentifier"}) {
+		t.Fatalf("unexpected pointer method set %v", got)
+	}
+
+	_, customer := findDecl(prj, stuffPkg, "Customer")
+	if got := names(customer.Named.ValueMethodSet); !reflect.DeepEqual(got, []string{"Identifier", "SomeMethod0"}) {
+		t.Fatalf("unexpected value method set %v", got)
+	}
+
+	if got := names(customer.Named.PointerMethodSet); !reflect.DeepEqual(got, []string{"Identifier", "SetIdentifier", "SomeMethod0"}) {
+		t.Fatalf("unexpected pointer method set %v", got)
+	}
+
+	_, someIface := findDecl(prj, stuffPkg, "SomeIface")
+	if len(someIface.Named.ValueMethodSet) != 0 || len(someIface.Named.PointerMethodSet) != 0 {
+		t.Fatal("interfaces have no computed method sets")
+	}
+}
+
+func TestProject_Implementations(t *testing.T) {
+	prj := loadTestProject(t)
+
+	someIfaceId, _ := findDecl(prj, stuffPkg, "SomeIface")
+	identifiableId, _ := findDecl(prj, stuffPkg, "Identifiable")
+	myStructId, _ := findDecl(prj, stuffPkg, "MyStruct")
+	entityId, _ := findDecl(prj, stuffPkg, "Entity")
+	customerId, _ := findDecl(prj, stuffPkg, "Customer")
+
+	impls := map[meta.DeclId]bool{}
+	for _, impl := range prj.Implementations(someIfaceId) {
+		impls[impl.DeclId] = impl.Pointer
+	}
+
+	if ptr, ok := impls[myStructId]; !ok || !ptr {
+		t.Fatalf("expected *MyStruct to implement SomeIface: %v", impls)
+	}
+
+	if ptr, ok := impls[customerId]; !ok || ptr {
+		t.Fatalf("expected Customer to implement SomeIface: %v", impls)
+	}
+
+	var ifaces []meta.Implementation
+	for _, impl := range prj.InterfacesOf(entityId) {
+		if impl.DeclId == identifiableId || impl.DeclId == someIfaceId {
+			ifaces = append(ifaces, impl)
+		}
+	}
+
+	if !reflect.DeepEqual(ifaces, []meta.Implementation{{DeclId: identifiableId, Pointer: true}}) {
+		t.Fatalf("unexpected interfaces of Entity %v", ifaces)
+	}
+
+	// interfaces which embed an instance or an alias are method sets as well
+	labeledId, _ := findDecl(prj, stuffPkg, "Labeled")
+	repoId, _ := findDecl(prj, stuffPkg, "MyStructRepo")
+	storeId, _ := findDecl(prj, stuffPkg, "MyStructStore")
+	impls = map[meta.DeclId]bool{}
+	for _, impl := range prj.Implementations(labeledId) {
+		impls[impl.DeclId] = impl.Pointer
+	}
+
+	if ptr, ok := impls[customerId]; !ok || !ptr {
+		t.Fatalf("expected *Customer to implement Labeled: %v", impls)
+	}
+
+	if impls := prj.Implementations(repoId); !reflect.DeepEqual(impls, []meta.Implementation{{DeclId: storeId}}) {
+		t.Fatalf("expected MyStructStore to implement MyStructRepo: %v", impls)
+	}
+}
+
+// TestImplementations_TypesImplements compares the implementations, which are computed from the table only, with
+// types.Implements for each pair of named types and interfaces of the fixtures and their dependencies.
+func TestImplementations_TypesImplements(t *testing.T) {
+	prj, err := NewProject(Options{
+		Dir:          "../internal/test",
+		Patterns:     []string{"github.com/golangee/..."},
+		CanonicalIds: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+		Dir:  "../internal/test",
+	}, "github.com/golangee/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scopes := map[string]*types.Scope{"": types.Universe}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		scopes[pkg.PkgPath] = pkg.Types.Scope()
+	})
+
+	// lookup resolves a canonical id of a package level type, like github.com/x/stuff.MyStruct or error
+	lookup := func(id meta.DeclId) types.Type {
+		path, name := "", string(id)
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			path, name = name[:i], name[i+1:]
+		}
+
+		if scope := scopes[path]; scope != nil {
+			if obj, ok := scope.Lookup(name).(*types.TypeName); ok && !obj.IsAlias() {
+				return obj.Type()
+			}
+		}
+
+		return nil
+	}
+
+	var ifaces, concretes []meta.DeclId
+	for _, id := range prj.table.DeclIds() {
+		named := prj.table.Declarations[id].Named
+		if named == nil || named.Func || len(named.TypeParams) > 0 {
+			continue
+		}
+
+		if lookup(id) == nil {
+			t.Fatalf("unknown type %s", id)
+		}
+
+		if iface, ok := lookup(id).Underlying().(*types.Interface); ok {
+			if iface.NumMethods() > 0 && iface.IsMethodSet() {
+				ifaces = append(ifaces, id)
+			}
+		} else {
+			concretes = append(concretes, id)
+		}
+	}
+
+	if len(ifaces) < 5 || len(concretes) < 20 {
+		t.Fatalf("expected more declarations, got %d interfaces and %d types", len(ifaces), len(concretes))
+	}
+
+	found := 0
+	for _, id := range concretes {
+		typ := lookup(id)
+
+		var expected []meta.Implementation
+		for _, ifaceId := range ifaces {
+			iface := lookup(ifaceId).Underlying().(*types.Interface)
+			switch {
+			case types.Implements(typ, iface):
+				expected = append(expected, meta.Implementation{DeclId: ifaceId})
+			case types.Implements(types.NewPointer(typ), iface):
+				expected = append(expected, meta.Implementation{DeclId: ifaceId, Pointer: true})
+			}
+		}
+
+		if got := prj.table.Declarations[id].Named.Implements; !reflect.DeepEqual(got, expected) {
+			t.Fatalf("%s: expected\n%v\nbut got\n%v", id, expected, got)
+		}
+
+		found += len(expected)
+	}
+
+	if found == 0 {
+		t.Fatal("expected implementations")
+	}
+}
+
+func TestProject_Constructors(t *testing.T) {
+	prj := loadTestProject(t)
+
+	constructors := func(name string) []string {
+		id, _ := findDecl(prj, stuffPkg, name)
+		var res []string
+		for _, c := range prj.Constructors(id) {
+			res = append(res, prj.table.Declarations[c].Named.Name)
+		}
+		return res
+	}
+
+	if got := constructors("MyStruct"); !reflect.DeepEqual(got, []string{"NewMyStruct"}) {
+		t.Fatalf("unexpected constructors %v", got)
+	}
+
+	if got := constructors("Customer"); !reflect.DeepEqual(got, []string{"NewCustomer"}) {
+		t.Fatalf("unexpected constructors %v", got)
+	}
+
+	if got := constructors("Entity"); !reflect.DeepEqual(got, []string{"MakeEntity"}) {
+		t.Fatalf("unexpected constructors %v", got)
+	}
+}
+
+func TestCanonicalIds(t *testing.T) {
+	prj, err := NewProject(Options{
+		Dir:          "../internal/test",
+		Patterns:     []string{"github.com/golangee/reflectplus/internal/test/internal/stuff"},
+		CanonicalIds: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[meta.DeclId]meta.DeclId{
+		stuffPkg + ".MyMap":     "map[string]*" + stuffPkg + ".MyStruct",
+		stuffPkg + ".MyChannel": "chan " + stuffPkg + ".MyStruct",
+		stuffPkg + ".MyArray":   "[3]uint8",
+		stuffPkg + ".MyFunc2":   "func(myInt " + stuffPkg + ".MyInt, a " + stuffPkg + ".MyInt) (" + stuffPkg + ".MyFunc, error)",
+		stuffPkg + ".Page":      "struct{Items []" + stuffPkg + ".Page#T; Total " + stuffPkg + ".Page#N; Next *" + stuffPkg + ".Page[" + stuffPkg + ".Page#T, " + stuffPkg + ".Page#N]}",
+		stuffPkg + ".Number":    "interface{~int | ~int64 | float64}",
+		stuffPkg + ".Sum":       "func[" + stuffPkg + ".Sum#N](values ..." + stuffPkg + ".Sum#N) (" + stuffPkg + ".Sum#N)",
+	}
+
+	for id, underlying := range expected {
+		named := prj.table.Declarations[id].Named
+		if named == nil {
+			t.Fatalf("expected named declaration %s", id)
+		}
+
+		if named.Underlying != underlying {
+			t.Fatalf("%s: expected\n%s\nbut got\n%s", id, underlying, named.Underlying)
+		}
+	}
+
+	method := prj.table.Declarations[stuffPkg+".MyStruct.SomeMethod0"].Named
+	if method == nil || method.Receiver != stuffPkg+".MyStruct" {
+		t.Fatalf("unexpected method %+v", method)
+	}
+
+	if _, ok := prj.table.Declarations["func (s *"+stuffPkg+".MyStruct) ()"]; !ok {
+		t.Fatal("expected method signature")
+	}
+
+	less := meta.DeclId("interface{Less(other " + stuffPkg + ".Sortable#T) (bool)}")
+	if prj.table.Declarations[less].Interface == nil {
+		t.Fatalf("expected anonymous interface %s", less)
+	}
+}
+
+func TestBuildOptions(t *testing.T) {
+	prj, err := NewProject(Options{
+		Dir:          "../internal/test",
+		Patterns:     []string{stuffPkg},
+		CanonicalIds: true,
+		Tags:         []string{"integration"},
+		GOOS:         "linux",
+		GOARCH:       "arm64",
+		Env:          []string{"CGO_ENABLED=0"},
+		Tests:        true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"IntegrationOnly", "TestOnly", "MyStruct"} {
+		if prj.table.Declarations[meta.DeclId(stuffPkg+"."+name)].Named == nil {
+			t.Fatalf("expected declaration %s", name)
+		}
+	}
+
+	defaultPrj := loadTestProject(t)
+	for _, name := range []string{"IntegrationOnly", "TestOnly"} {
+		if id, _ := findDecl(defaultPrj, stuffPkg, name); id != "" {
+			t.Fatalf("unexpected declaration %s", name)
+		}
+	}
+}
+
+func TestPlatforms(t *testing.T) {
+	linux := meta.Platform{GOOS: "linux", GOARCH: "amd64"}
+	windows := meta.Platform{GOOS: "windows", GOARCH: "amd64", Tags: []string{"integration"}}
+	prj, err := NewProject(Options{
+		Dir:          "../internal/test",
+		Patterns:     []string{stuffPkg},
+		CanonicalIds: true,
+		Platforms:    []meta.Platform{linux, windows},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]meta.Platform{
+		"MyStruct":        {linux, windows},
+		"NewMyStruct":     {linux, windows},
+		"WindowsOnly":     {windows},
+		"IntegrationOnly": {windows},
+	}
+
+	for name, platforms := range expected {
+		named := prj.table.Declarations[meta.DeclId(stuffPkg+"."+name)].Named
+		if named == nil {
+			t.Fatalf("expected declaration %s", name)
+		}
+
+		if !reflect.DeepEqual(named.Platforms, platforms) {
+			t.Fatalf("%s: unexpected platforms %v", name, named.Platforms)
+		}
+	}
+
+	if c := prj.table.Declarations[stuffPkg+".IntegrationOnly"].Named.BuildConstraint; c != "integration" {
+		t.Fatalf("unexpected build constraint '%s'", c)
+	}
+
+	if c := prj.table.Declarations[stuffPkg+".MyStruct"].Named.BuildConstraint; c != "" {
+		t.Fatalf("unexpected build constraint '%s'", c)
+	}
+
+	// the file name platform_windows.go implies the constraint for each kind of declaration
+	windowsOnly := prj.table.Declarations[stuffPkg+".WindowsOnly"].Named
+	separator := prj.table.Declarations[stuffPkg+".WindowsSeparator"].Const
+	handles := prj.table.Declarations[stuffPkg+".WindowsHandles"].Var
+	resourceAlias := prj.table.Declarations[stuffPkg+".WindowsResource"].Alias
+	for _, c := range []string{
+		windowsOnly.BuildConstraint, separator.BuildConstraint, handles.BuildConstraint, resourceAlias.BuildConstraint,
+	} {
+		if c != "windows" {
+			t.Fatalf("unexpected build constraint '%s'", c)
+		}
+	}
+
+	// the first platform misses the windows only methods and constructors of a declaration of both platforms
+	resource := prj.table.Declarations[stuffPkg+".Resource"].Named
+	if !reflect.DeepEqual(resource.Constructors, []meta.DeclId{stuffPkg + ".NewResource"}) {
+		t.Fatalf("unexpected constructors %v", resource.Constructors)
+	}
+
+	if !reflect.DeepEqual(resource.Implements, []meta.Implementation{{DeclId: stuffPkg + ".Releaser", Pointer: true}}) {
+		t.Fatalf("unexpected implementations %v", resource.Implements)
+	}
+
+	if !slices.Contains(resource.PointerMethodSet, stuffPkg+".Resource.Release") {
+		t.Fatalf("unexpected method set %v", resource.PointerMethodSet)
+	}
+
+	consts := map[string][]meta.Platform{
+		"DefaultName":      {linux, windows},
+		"WindowsSeparator": {windows},
+	}
+
+	for name, platforms := range consts {
+		c := prj.table.Declarations[meta.DeclId(stuffPkg+"."+name)].Const
+		if c == nil || !reflect.DeepEqual(c.Platforms, platforms) {
+			t.Fatalf("%s: unexpected constant %+v", name, c)
+		}
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	dir, err := filepath.Abs("../internal/test/internal/stuff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prj, err := NewProject(Options{
+		Dir:          "../internal/test",
+		Patterns:     []string{stuffPkg},
+		CanonicalIds: true,
+		Overlay: map[string][]byte{
+			filepath.Join(dir, "unsaved.go"): []byte("package stuff\n\n// Unsaved is only in the editor\ntype Unsaved int\n"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	named := prj.table.Declarations[stuffPkg+".Unsaved"].Named
+	if named == nil || named.Doc != "Unsaved is only in the editor" {
+		t.Fatalf("unexpected overlay declaration %+v", named)
+	}
+}
+
+func TestStripFuncBodies(t *testing.T) {
+	const code = `package stuff
+
+// Hello doc
+func Hello() string {
+	// inner comment
+	return "world"
+}
+
+var Fn = func() int {
+	// literal comment
+	return 1
+}
+
+// trailing comment
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "stuff.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bodies, bytes := stripFuncBodies(file)
+	if bodies != 1 || bytes != strings.Index(code, "\n\nvar")-strings.Index(code, "{") {
+		t.Fatalf("unexpected savings %d %d", bodies, bytes)
+	}
+
+	var comments []string
+	for _, group := range file.Comments {
+		comments = append(comments, group.Text())
+	}
+
+	if !reflect.DeepEqual(comments, []string{"Hello doc\n", "literal comment\n", "trailing comment\n"}) {
+		t.Fatalf("unexpected comments %v", comments)
+	}
+
+	fun := file.Decls[0].(*ast.FuncDecl)
+	if fun.Doc.Text() != "Hello doc\n" || fset.Position(fun.End()).Line != 7 {
+		t.Fatal("expected the declaration to be unchanged")
+	}
+
+	prj := loadTestProject(t)
+	if stats := prj.Stats(); stats.Files == 0 || stats.StrippedBodies == 0 || stats.StrippedBytes == 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestDropUnusedImportErrors(t *testing.T) {
+	const code = `package stuff
+
+import "strings"
+
+func Upper(s string) string {
+	return strings.ToUpper(s)
+}
+
+var Broken Undefined
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "stuff.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stripFuncBodies(file)
+
+	pkg := &packages.Package{ID: "stuff"}
+	conf := types.Config{
+		Importer: importer.Default(),
+		Error: func(err error) {
+			pkg.TypeErrors = append(pkg.TypeErrors, err.(types.Error))
+			pkg.Errors = append(pkg.Errors, packages.Error{Msg: err.(types.Error).Msg, Kind: packages.TypeError})
+		},
+	}
+	_, _ = conf.Check("stuff", fset, []*ast.File{file}, nil)
+
+	if len(pkg.TypeErrors) != 2 {
+		t.Fatalf("expected the unused import and the undefined type but got %v", pkg.TypeErrors)
+	}
+
+	dropUnusedImportErrors([]*packages.Package{pkg})
+	if len(pkg.TypeErrors) != 1 || len(pkg.Errors) != 1 || pkg.Errors[0].Msg != "undefined: Undefined" {
+		t.Fatalf("expected only the undefined type but got %v", pkg.Errors)
+	}
+}
+
+func TestDependencySyntax(t *testing.T) {
+	const uuidPkg = "github.com/golangee/uuid"
+
+	load := func(depSyntax bool) *Project {
+		prj, err := NewProject(Options{
+			Dir:              "../internal/test",
+			Patterns:         []string{stuffPkg},
+			DependencySyntax: depSyntax,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return prj
+	}
+
+	prj := load(false)
+	if _, uuid := findDecl(prj, uuidPkg, "UUID"); uuid.Named == nil {
+		t.Fatal("expected the referenced dependency declaration")
+	}
+
+	// only referenced declarations of dependencies are materialized
+	if id, _ := findDecl(prj, uuidPkg, "New"); id != "" {
+		t.Fatal("unexpected unreferenced dependency declaration")
+	}
+
+	srcPrj := load(true)
+	if _, uuid := findDecl(srcPrj, uuidPkg, "UUID"); uuid.Named == nil {
+		t.Fatal("expected the referenced dependency declaration")
+	}
+
+	if prj.Stats().Files >= srcPrj.Stats().Files {
+		t.Fatalf("expected only the files of the patterns to be parsed: %+v vs %+v", prj.Stats(), srcPrj.Stats())
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	var results []string
+	var orders [][]string
+	for _, workers := range []int{1, 8, 2, 8, 4} {
+		prj, err := NewProject(Options{
+			Dir:      "../internal/test",
+			Patterns: []string{"github.com/golangee/reflectplus/internal/test/..."},
+			Workers:  workers,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the files of a package are parsed concurrently, so their order must not leak into the declarations
+		var order []string
+		pid, _ := prj.table.PackageByImportPath(stuffPkg)
+		for _, id := range prj.table.Packages[pid].Declarations {
+			order = append(order, string(id))
+		}
+
+		orders = append(orders, order)
+		if !reflect.DeepEqual(orders[0], order) {
+			t.Fatalf("expected the same declaration order regardless of the amount of workers: %d", workers)
+		}
+
+		results = append(results, prj.String())
+		if results[0] != results[len(results)-1] {
+			t.Fatalf("expected the same table regardless of the amount of workers: %d", workers)
+		}
+	}
+
+	// the files of a package are parsed concurrently, so the same package is put with its files in any order
+	files := map[string]string{
+		"a.go": "package order\n\ntype A struct{ C *C }\n\nfunc NewA() A { return A{} }\n",
+		"b.go": "package order\n\nconst B = 1\n\ntype Bs []C\n",
+		"c.go": "package order\n\ntype C int\n\nvar D C\n",
+	}
+	var fileOrders [][]string
+	for _, names := range [][]string{{"a.go", "b.go", "c.go"}, {"c.go", "b.go", "a.go"}, {"b.go", "c.go", "a.go"}} {
+		order := putFiles(t, files, names)
+		fileOrders = append(fileOrders, order)
+		if !reflect.DeepEqual(fileOrders[0], order) {
+			t.Fatalf("expected the same declaration order regardless of the parse order: %v vs %v", fileOrders[0], order)
+		}
+	}
+}
+
+// putFiles parses the files in the given order and returns the canonical ids of the declarations of the package.
+func putFiles(t *testing.T, files map[string]string, names []string) []string {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	index := newPosIndex(fset)
+	var syntax []*ast.File
+	for _, name := range names {
+		file, err := parser.ParseFile(fset, name, files[name], parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		index.merge(indexFile(fset, file))
+		syntax = append(syntax, file)
+	}
+
+	info := &types.Info{Defs: map[*ast.Ident]types.Object{}}
+	tpkg, err := (&types.Config{}).Check("order", fset, syntax, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pctx := &parseCtx{
+		fset:       fset,
+		index:      index,
+		canonical:  true,
+		typeParams: map[*types.TypeParam]meta.DeclId{},
+		pending:    map[meta.DeclId]bool{},
+	}
+
+	table := meta.NewTable()
+	pkg := &packages.Package{Types: tpkg, TypesInfo: info, Syntax: syntax}
+	if err := putPackage(context.Background(), table, pctx, pkg); err != nil {
+		t.Fatal(err)
+	}
+
+	pid, _ := table.PackageByImportPath("order")
+	var res []string
+	for _, id := range table.Packages[pid].Declarations {
+		res = append(res, string(id))
+	}
+
+	return res
+}
+
+func TestCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "test")
+	if err := os.CopyFS(dir, os.DirFS("../internal/test")); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{
+		Dir:      dir,
+		Patterns: []string{"github.com/golangee/..."},
+		CacheDir: t.TempDir(),
+	}
+
+	load := func() *Project {
+		prj, err := NewProject(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return prj
+	}
+
+	fresh := load()
+	if fresh.Stats().CachedPackages != 0 {
+		t.Fatalf("unexpected cache hits %+v", fresh.Stats())
+	}
+
+	cached := load()
+	if cached.Stats().CachedPackages == 0 || cached.Stats().Files != 0 {
+		t.Fatalf("expected only cache hits %+v", cached.Stats())
+	}
+
+	if fresh.String() != cached.String() {
+		t.Fatal("expected the same table from the cache")
+	}
+
+	// the domain is parsed again and refers to stuff, which is only loaded from the export data
+	err := os.WriteFile(filepath.Join(dir, "internal", "domain", "changed.go"), []byte(`package domain
+
+import "github.com/golangee/reflectplus/internal/test/internal/stuff"
+
+// Changed refers to another package
+type Changed stuff.MyStruct
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := load()
+	if hits := changed.Stats().CachedPackages; hits == 0 || hits >= cached.Stats().CachedPackages {
+		t.Fatalf("expected only the changed packages to be parsed %+v", changed.Stats())
+	}
+
+	if id, _ := findDecl(changed, "github.com/golangee/reflectplus/internal/test/internal/domain", "Changed"); id == "" {
+		t.Fatal("expected the changed declaration")
+	}
+
+	// the underlying struct is shared with the changed type, but must contain the docs of its own package
+	_, expected := findDecl(fresh, stuffPkg, "MyStruct")
+	_, myStruct := findDecl(changed, stuffPkg, "MyStruct")
+	x, _ := json.Marshal(fresh.table.Declarations[expected.Named.Underlying])
+	y, _ := json.Marshal(changed.table.Declarations[myStruct.Named.Underlying])
+	if expected.Named.Doc != myStruct.Named.Doc || string(x) != string(y) {
+		t.Fatalf("expected the declaration of the own package\n%s\n%s", x, y)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	if prj := loadTestProject(t); len(prj.Diagnostics()) > 0 {
+		t.Fatalf("unexpected diagnostics %v", prj.Diagnostics())
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module source\n\ngo 1.22\n",
+		"broken/broken.go": `package broken
+
+import "fmt"
+
+// Valid is kept, although the package is broken
+type Valid struct{}
+
+// Broken refers to an undefined type
+type Broken struct {
+	Field Undefined
+}
+
+// Holder is omitted, because it depends on Broken
+type Holder struct {
+	Broken *Broken
+}
+
+func Print() {
+	fmt.Println()
+}
+`,
+		"valid/valid.go": `package valid
+
+// Annotated has a malformed annotation
+// @Entity(
+type Annotated struct {
+	Name string
+}
+`,
+	}
+
+	for name, content := range files {
+		fname := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prj, err := NewProject(Options{Dir: dir, Patterns: []string{"./..."}, CanonicalIds: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, d := range prj.Diagnostics() {
+		file, line, col := d.Location.Split()
+		got = append(got, fmt.Sprintf("%s:%d:%d %s %s %s %s", filepath.Base(file), line, col, d.Severity, d.Kind, d.DeclId, d.Message))
+	}
+
+	expected := []string{
+		"broken.go:9:6 error unsupported source/broken.Broken Broken: invalid type",
+		"broken.go:10:8 error type source/broken.Broken undefined: Undefined",
+		"broken.go:14:6 error unsupported source/broken.Holder Holder: invalid type",
+		"valid.go:5:6 error annotation source/valid.Annotated ParserError: @Entity(:1: unbalanced open/close argument braces",
+	}
+
+	if !reflect.DeepEqual(expected, got) || !prj.HasErrors() {
+		t.Fatalf("expected\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	if id, decl := findDecl(prj, "source/valid", "Annotated"); id == "" || len(decl.Named.Annotations) != 0 {
+		t.Fatal("expected the declaration without annotations")
+	}
+
+	if id, _ := findDecl(prj, "source/broken", "Valid"); id == "" {
+		t.Fatal("expected the valid declaration of the broken package")
+	}
+
+	for _, name := range []string{"Broken", "Holder"} {
+		if id, _ := findDecl(prj, "source/broken", name); id != "" {
+			t.Fatalf("expected the broken declaration %s to be omitted", name)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	// nothing must be written to stdout, because the json output is usually piped into other tools
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	phases := map[string][]Phase{}
+	var bulk []Progress
+	var last Progress
+	logs := &strings.Builder{}
+	cacheDir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		_, err = NewProject(Options{
+			Dir:      "../internal/test",
+			Patterns: []string{"github.com/golangee/reflectplus/internal/test/..."},
+			CacheDir: cacheDir,
+			Logger:   slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+			Progress: func(p Progress) {
+				switch {
+				case p.Package == "":
+					bulk = append(bulk, p)
+				case i == 0:
+					phases[p.Package] = append(phases[p.Package], p.Phase)
+				}
+				last = p
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_ = w.Close()
+	out, _ := io.ReadAll(r)
+	if len(out) > 0 {
+		t.Fatalf("unexpected output %s", out)
+	}
+
+	if !strings.Contains(logs.String(), "patterns=[github.com/golangee/reflectplus/internal/test/...]") {
+		t.Fatalf("expected debug logs but got %s", logs.String())
+	}
+
+	expected := []Phase{PhaseTable}
+	if len(phases) == 0 || !reflect.DeepEqual(phases[stuffPkg], expected) {
+		t.Fatalf("unexpected phases %v", phases)
+	}
+
+	// the second run loads all packages from the cache and type checks nothing
+	total := len(phases)
+	expectedBulk := []Progress{
+		{Phase: PhaseLoad, Done: total, Total: total},
+		{Phase: PhaseTypeCheck, Done: total, Total: total},
+		{Phase: PhaseLoad, Done: total, Total: total},
+	}
+	if !reflect.DeepEqual(bulk, expectedBulk) {
+		t.Fatalf("unexpected bulk progress %+v", bulk)
+	}
+
+	for pkg, got := range phases {
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("%s: unexpected phases %v", pkg, got)
+		}
+	}
+
+	if last.Phase != PhaseTable || last.Done != len(phases) || last.Total != len(phases) || !last.Cached {
+		t.Fatalf("unexpected progress %+v", last)
+	}
+}
+
+func TestNewProjectContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	opts := Options{
+		Dir:      "../internal/test",
+		Patterns: []string{"github.com/golangee/reflectplus/internal/test/..."},
+	}
+
+	if _, err := NewProjectContext(ctx, opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation but got %v", err)
+	}
+
+	// cancel while the tables are built
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	opts.Workers = 1
+	opts.Progress = func(p Progress) {
+		if p.Phase == PhaseTypeCheck {
+			cancel()
+		}
+	}
+
+	if _, err := NewProjectContext(ctx, opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation but got %v", err)
+	}
+}
+
+func TestScope(t *testing.T) {
+	load := func(scoped bool, depth int) *Project {
+		prj, err := NewProject(Options{
+			Dir:          "../internal/test",
+			Patterns:     []string{"github.com/golangee/reflectplus/internal/test/..."},
+			CanonicalIds: true,
+			Scoped:       scoped,
+			ScopeDepth:   depth,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for id, decl := range prj.table.Declarations {
+			for _, ref := range decl.References() {
+				if !prj.table.HasDeclaration(ref) {
+					t.Fatalf("%s refers to the missing declaration %s", id, ref)
+				}
+			}
+		}
+
+		return prj
+	}
+
+	all := load(false, 0)
+	names := load(true, 0)
+	direct := load(true, 1)
+
+	_, stuffAll := findDecl(all, stuffPkg, "MyStruct")
+	_, stuffNames := findDecl(names, stuffPkg, "MyStruct")
+	if !reflect.DeepEqual(stuffAll, stuffNames) {
+		t.Fatal("expected the declarations of the patterns to be kept")
+	}
+
+	const uuid = "github.com/golangee/uuid.UUID"
+	if named := names.table.Declarations[uuid].Named; named == nil || !named.Opaque || named.Underlying != "" ||
+		len(named.Methods) > 0 {
+		t.Fatalf("expected an opaque dependency type %+v", named)
+	}
+
+	if _, ok := names.table.PackageByImportPath("database/sql/driver"); ok {
+		t.Fatal("expected the unreferenced package to be removed")
+	}
+
+	if named := direct.table.Declarations[uuid].Named; named == nil || named.Opaque || len(named.Methods) == 0 {
+		t.Fatalf("expected the complete dependency type %+v", named)
+	}
+
+	if named := direct.table.Declarations["database/sql/driver.Value"].Named; named == nil || !named.Opaque {
+		t.Fatalf("expected an opaque transitive dependency type %+v", named)
+	}
+
+	if len(names.table.Declarations) >= len(direct.table.Declarations) ||
+		len(direct.table.Declarations) > len(all.table.Declarations) {
+		t.Fatal("expected less declarations for a smaller depth")
+	}
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	prj := loadTestProject(t)
+	if err := prj.table.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	treeId, tree := findDecl(prj, stuffPkg, "Tree")
+	forestId, forest := findDecl(prj, stuffPkg, "Forest")
+	fields := prj.table.Declarations[tree.Named.Underlying].Struct.Fields
+	children := prj.table.Declarations[fields[0].DeclId].Slice
+	if children == nil || prj.table.Declarations[children.DeclId].Pointer.Base != treeId {
+		t.Fatalf("expected a self reference %+v", fields[0])
+	}
+
+	if prj.table.Declarations[fields[1].DeclId].Pointer.Base != forestId {
+		t.Fatalf("expected a reference to the forest %+v", fields[1])
+	}
+
+	trees := prj.table.Declarations[prj.table.Declarations[forest.Named.Underlying].Struct.Fields[0].DeclId].Map
+	if trees == nil || trees.Value != treeId {
+		t.Fatal("expected a reference back to the tree")
+	}
+
+	if len(tree.Named.Methods) != 1 || prj.table.Declarations[tree.Named.Methods[0]].Named.Receiver != treeId {
+		t.Fatalf("unexpected methods %v", tree.Named.Methods)
+	}
+}
+
+func TestForwardReferences(t *testing.T) {
+	// the type checker resolves the undefined type as invalid, while Broken is still under construction
+	const code = `package broken
+
+type Broken struct {
+	Self  *Broken
+	Field Undefined
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "broken.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := types.Config{Error: func(err error) {}}
+	pkg, _ := conf.Check("broken", fset, []*ast.File{file}, nil)
+
+	ctx := &parseCtx{
+		fset:       fset,
+		index:      indexFile(fset, file),
+		canonical:  true,
+		typeParams: map[*types.TypeParam]meta.DeclId{},
+		pending:    map[meta.DeclId]bool{},
+	}
+
+	table := meta.NewTable()
+	if _, err := putType(table, ctx, pkg.Scope().Lookup("Broken").Type()); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if table.HasDeclaration("broken.Broken") || len(ctx.pending) > 0 {
+		t.Fatal("expected no placeholder of the broken declaration")
+	}
+
+	// the pointer to Broken has been put before the error, but must not be left behind
+	if table.HasDeclaration("*broken.Broken") {
+		t.Fatal("expected no reference to the broken declaration")
+	}
+
+	if err := table.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseCtx_Validate(t *testing.T) {
+	table := meta.NewTable()
+	table.PutPackageDeclaration("stuff", "stuff", "dangling", meta.Type{Pointer: &meta.Pointer{Base: "missing"}})
+	table.PutPackageDeclaration("stuff", "stuff", "referrer", meta.Type{Slice: &meta.Slice{DeclId: "dangling"}})
+	table.PutPackageDeclaration("stuff", "stuff", "valid", meta.Type{Basic: &meta.Basic{Kind: meta.Int}})
+
+	ctx := &parseCtx{}
+	ctx.validate(table)
+
+	// the package is kept without the invalid declaration and its referrer
+	if err := table.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	pid, _ := table.PackageByImportPath("stuff")
+	got := table.Packages[pid].Declarations
+	if !reflect.DeepEqual(got, []meta.DeclId{"valid"}) || len(table.Declarations) != 1 {
+		t.Fatalf("unexpected declarations %v", got)
+	}
+
+	if len(ctx.diagnostics) != 1 || ctx.diagnostics[0].DeclId != "dangling" {
+		t.Fatalf("unexpected diagnostics %v", ctx.diagnostics)
+	}
+}
+
+func TestDeclaredTypes(t *testing.T) {
+	prj := loadTestProject(t)
+
+	// MyHopHopString -> MyHopString -> MyString -> string
+	var chain []string
+	id, decl := findDecl(prj, stuffPkg, "MyHopHopString")
+	for decl.Named != nil {
+		chain = append(chain, decl.Named.Name)
+		if prj.table.Declarations[decl.Named.Underlying].Basic == nil {
+			t.Fatalf("expected the resolved basic type of %s", id)
+		}
+
+		id = decl.Named.Declared
+		decl = prj.table.Declarations[id]
+	}
+
+	if decl.Basic == nil || decl.Basic.Kind != meta.String {
+		t.Fatalf("expected the chain to end with a string but got %+v", decl)
+	}
+
+	expected := []string{"MyHopHopString", "MyHopString", "MyString"}
+	if !reflect.DeepEqual(expected, chain) {
+		t.Fatalf("expected %v but got %v", expected, chain)
+	}
+
+	// a type literal is declared as its underlying type
+	if _, myStruct := findDecl(prj, stuffPkg, "MyStruct"); myStruct.Named.Declared != myStruct.Named.Underlying {
+		t.Fatalf("expected the struct literal as declared type %+v", myStruct.Named)
+	}
+
+	// the syntax of dependencies is not available
+	stuffPrj, err := NewProject(Options{Dir: "../internal/test", Patterns: []string{stuffPkg}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, uuid := findDecl(stuffPrj, "github.com/golangee/uuid", "UUID"); uuid.Named == nil || uuid.Named.Declared != "" {
+		t.Fatalf("unexpected dependency %+v", uuid.Named)
+	}
+}
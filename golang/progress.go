@@ -0,0 +1,117 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"sync"
+)
+
+// Phase is a step of the parsing process, which each package passes through in order.
+type Phase string
+
+const (
+	// PhaseLoad means that the build system has listed the files and imports of all packages. The build system
+	// provides no progress of single packages, so this is reported only once for all packages.
+	PhaseLoad Phase = "load"
+
+	// PhaseTypeCheck means that all packages have been parsed and type checked. Like PhaseLoad, this is a single
+	// step of the build system, which is reported only once after the last package has been checked.
+	PhaseTypeCheck Phase = "typecheck"
+
+	// PhaseTable means that the declarations of the package have been put into the table or that the table
+	// has been loaded from the cache.
+	PhaseTable Phase = "table"
+)
+
+// Progress is reported each time a package has completed a phase.
+type Progress struct {
+	Phase Phase
+
+	// Package is the id of the package, which has completed the phase. It is empty for PhaseLoad and
+	// PhaseTypeCheck, which complete for all packages at once.
+	Package string
+
+	// Done is the amount of packages, which have completed the phase, including this one.
+	Done int
+
+	// Total is the amount of packages of the phase, which is less for the type checking, if packages are cached.
+	// Done equals Total for the phases, which complete for all packages at once.
+	Total int
+
+	// Cached is true, if the table of the package has been loaded from the cache, see also Options.CacheDir.
+	Cached bool
+}
+
+// A progressReporter counts the packages of each phase and serializes the calls of the callback, which may be
+// nil.
+type progressReporter struct {
+	mutex    sync.Mutex
+	callback func(Progress)
+	totals   map[Phase]int
+	done     map[Phase]int
+}
+
+func newProgressReporter(callback func(Progress)) *progressReporter {
+	return &progressReporter{
+		callback: callback,
+		totals:   map[Phase]int{},
+		done:     map[Phase]int{},
+	}
+}
+
+// start announces the amount of packages of the phase.
+func (r *progressReporter) start(phase Phase, total int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.totals[phase] = total
+}
+
+// report notifies the callback about the completed package.
+func (r *progressReporter) report(phase Phase, pkgId string, cached bool) {
+	if r.callback == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.done[phase]++
+	r.callback(Progress{
+		Phase:   phase,
+		Package: pkgId,
+		Done:    r.done[phase],
+		Total:   r.totals[phase],
+		Cached:  cached,
+	})
+}
+
+// reportAll notifies the callback once about a phase, which the build system completes for all packages at once.
+func (r *progressReporter) reportAll(phase Phase, total int) {
+	if r.callback == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.totals[phase] = total
+	r.done[phase] = total
+	r.callback(Progress{
+		Phase: phase,
+		Done:  total,
+		Total: total,
+	})
+}
@@ -5,17 +5,67 @@ import (
 	"github.com/golangee/reflectplus/meta"
 	"github.com/golangee/src"
 	"reflect"
+	"sort"
 )
 
 type Project struct {
 	table       *meta.Table
 	importTable map[meta.DeclId]meta.PkgId
+	stats       Stats
+	diagnostics []Diagnostic
+}
+
+// Stats contains some metrics about the parsing process.
+type Stats struct {
+	// Files is the amount of parsed files, including those of the dependencies.
+	Files int
+
+	// StrippedBodies is the amount of removed function bodies, see also Options.KeepFuncBodies.
+	StrippedBodies int
+
+	// StrippedBytes is the size of the source code of the removed function bodies, which has not to be kept
+	// and type checked.
+	StrippedBytes int
+
+	// CachedPackages is the amount of packages, whose tables have been loaded from the cache, see also
+	// Options.CacheDir.
+	CachedPackages int
+}
+
+// add sums up the metrics.
+func (s *Stats) add(other Stats) {
+	s.Files += other.Files
+	s.StrippedBodies += other.StrippedBodies
+	s.StrippedBytes += other.StrippedBytes
+	s.CachedPackages += other.CachedPackages
 }
 
 func (p *Project) String() string {
 	return p.table.String()
 }
 
+// Stats returns the metrics of the parsing process.
+func (p *Project) Stats() Stats {
+	return p.stats
+}
+
+// Diagnostics returns the problems of all packages, ordered by their location. Declarations which are affected by an
+// error may be missing.
+func (p *Project) Diagnostics() []Diagnostic {
+	return p.diagnostics
+}
+
+// HasErrors returns true, if any diagnostic is an error, see also Diagnostics.
+func (p *Project) HasErrors() bool {
+	for _, d := range p.diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (p *Project) ForEachTypeAnnotation(annotationName string, f func(a meta.Annotation, named *meta.Named)) {
 	for _, v := range p.table.Declarations {
 		if v.Named != nil {
@@ -42,30 +92,168 @@ func (p *Project) ForEachInterface(f func(pkg *meta.Package, id meta.DeclId, nam
 	}
 }
 
+// ForEachFunc loops over all package level function declarations in a stable order.
+func (p *Project) ForEachFunc(f func(pkg *meta.Package, id meta.DeclId, named *meta.Named, sig *meta.Signature)) {
+	for _, id := range p.table.DeclIds() {
+		v := p.table.Declarations[id]
+		if v.Named != nil && v.Named.Func && v.Named.Receiver == "" {
+			pkgId := p.importTable[id]
+			pkg := p.table.Packages[pkgId]
+			f(pkg, id, v.Named, p.table.Declarations[v.Named.Underlying].Signature)
+		}
+	}
+}
+
+// ForEachConst loops over all package level constant declarations in a stable order.
+func (p *Project) ForEachConst(f func(pkg *meta.Package, id meta.DeclId, c *meta.Const)) {
+	for _, id := range p.table.DeclIds() {
+		v := p.table.Declarations[id]
+		if v.Const != nil {
+			pkgId := p.importTable[id]
+			pkg := p.table.Packages[pkgId]
+			f(pkg, id, v.Const)
+		}
+	}
+}
+
+// ForEachVar loops over all package level variable declarations in a stable order.
+func (p *Project) ForEachVar(f func(pkg *meta.Package, id meta.DeclId, v *meta.Var)) {
+	for _, id := range p.table.DeclIds() {
+		v := p.table.Declarations[id]
+		if v.Var != nil {
+			pkgId := p.importTable[id]
+			pkg := p.table.Packages[pkgId]
+			f(pkg, id, v.Var)
+		}
+	}
+}
+
+// EnumValues returns all constants of the given type id in declaration order, e.g. to generate String or
+// Parse methods for a type Status int enumeration.
+func (p *Project) EnumValues(id meta.DeclId) []*meta.Const {
+	var res []*meta.Const
+	p.ForEachConst(func(pkg *meta.Package, _ meta.DeclId, c *meta.Const) {
+		if c.DeclId == id {
+			res = append(res, c)
+		}
+	})
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Location.Less(res[j].Location)
+	})
+
+	return res
+}
+
+// Implementations returns all named types of the project, which implement the named interface with the given id,
+// in a stable order.
+func (p *Project) Implementations(id meta.DeclId) []meta.Implementation {
+	var res []meta.Implementation
+	for _, typeId := range p.table.DeclIds() {
+		v := p.table.Declarations[typeId]
+		if v.Named == nil {
+			continue
+		}
+
+		for _, impl := range v.Named.Implements {
+			if impl.DeclId == id {
+				res = append(res, meta.Implementation{DeclId: typeId, Pointer: impl.Pointer})
+			}
+		}
+	}
+
+	return res
+}
+
+// InterfacesOf returns all named interfaces of the project, which are implemented by the named type with the
+// given id.
+func (p *Project) InterfacesOf(id meta.DeclId) []meta.Implementation {
+	v := p.table.Declarations[id]
+	if v.Named == nil {
+		return nil
+	}
+
+	return v.Named.Implements
+}
+
+// Constructors returns the package level functions, which create the named type with the given id.
+func (p *Project) Constructors(id meta.DeclId) []meta.DeclId {
+	v := p.table.Declarations[id]
+	if v.Named == nil {
+		return nil
+	}
+
+	return v.Named.Constructors
+}
+
 func (p *Project) TypeDecl(id meta.DeclId) *src.TypeDecl {
+	return p.typeDecl(id, nil)
+}
+
+// typeDecl creates the declaration and replaces any type parameter contained in typeArgs by its type argument.
+func (p *Project) typeDecl(id meta.DeclId, typeArgs map[meta.DeclId]meta.DeclId) *src.TypeDecl {
+	if arg, ok := typeArgs[id]; ok {
+		// the type argument belongs to the outer scope and is never substituted again
+		return p.TypeDecl(arg)
+	}
+
 	declaredType := p.table.Declarations[id]
 
 	switch t := declaredType.Kind().(type) {
 	case *meta.Basic:
 		return src.NewTypeDecl(src.Qualifier(t.Kind.String()))
 	case *meta.Named:
-		pkg := p.table.Packages[p.importTable[id]]
-		return src.NewTypeDecl(src.Qualifier(pkg.Path + "." + t.Name))
+		return src.NewTypeDecl(p.qualifier(id, t.Name))
+	case *meta.Alias:
+		return src.NewTypeDecl(p.qualifier(id, t.Name))
+	case *meta.TypeParam:
+		return src.NewTypeDecl(src.Qualifier(t.Name))
+	case *meta.Instance:
+		// the src emitter has no notion of type arguments, so we emit the brackets as tokens, which
+		// are fixed by the final formatting. The origin is always a Named or an Alias.
+		params := []*src.TypeDecl{src.NewTypeDecl("[")}
+		for i, arg := range t.TypeArgs {
+			if i > 0 {
+				params = append(params, src.NewTypeDecl(","))
+			}
+			params = append(params, p.typeDecl(arg, typeArgs))
+		}
+		params = append(params, src.NewTypeDecl("]"))
+
+		name := ""
+		if origin := p.table.Declarations[t.Origin]; origin.Named != nil {
+			name = origin.Named.Name
+		} else {
+			name = origin.Alias.Name
+		}
+
+		return src.NewGenericDecl(p.qualifier(t.Origin, name), params...)
 	case *meta.Map:
-		return src.NewMapDecl(p.TypeDecl(t.Key), p.TypeDecl(t.Value))
+		return src.NewMapDecl(p.typeDecl(t.Key, typeArgs), p.typeDecl(t.Value, typeArgs))
 	case *meta.Slice:
-		return src.NewSliceDecl(p.TypeDecl(t.DeclId))
+		return src.NewSliceDecl(p.typeDecl(t.DeclId, typeArgs))
 	case *meta.Channel:
-		return src.NewChanDecl(p.TypeDecl(t.DeclId))
+		return src.NewChanDecl(p.typeDecl(t.DeclId, typeArgs))
 	case *meta.Pointer:
-		return src.NewPointerDecl(p.TypeDecl(t.Base))
+		return src.NewPointerDecl(p.typeDecl(t.Base, typeArgs))
 	case *meta.Array:
-		return src.NewArrayDecl(t.Len, p.TypeDecl(t.DeclId))
+		return src.NewArrayDecl(t.Len, p.typeDecl(t.DeclId, typeArgs))
 	default:
 		panic(reflect.TypeOf(t))
 	}
 }
 
+// qualifier returns the qualified name of a package level declaration. Declarations of the universe scope, like
+// error or any, have no package path, so that only their name is returned, just like for a Basic type.
+func (p *Project) qualifier(id meta.DeclId, name string) src.Qualifier {
+	pkg := p.table.Packages[p.importTable[id]]
+	if pkg == nil || pkg.Path == "" {
+		return src.Qualifier(name)
+	}
+
+	return src.Qualifier(pkg.Path + "." + name)
+}
+
 type MethodContext struct {
 	TypeAnnotations   []meta.Annotation
 	TypeImpl          *src.TypeBuilder
@@ -73,15 +261,36 @@ type MethodContext struct {
 	Method            *src.FuncBuilder
 }
 
+// Implement creates a struct which implements the interface with the given id. A generic interface must be
+// given as an instantiation, e.g. Repository[MyStruct], so that its type parameters can be substituted.
 func (p *Project) Implement(id meta.DeclId, f func(ctx MethodContext)) (*src.TypeBuilder, error) {
+	var typeArgs map[meta.DeclId]meta.DeclId
+	if inst := p.table.Declarations[id].Instance; inst != nil {
+		origin := p.table.Declarations[inst.Origin]
+		if origin.Named == nil {
+			return nil, fmt.Errorf("%s is not an instance of a named type", id)
+		}
+
+		typeArgs = map[meta.DeclId]meta.DeclId{}
+		for i, param := range origin.Named.TypeParams {
+			typeArgs[param] = inst.TypeArgs[i]
+		}
+
+		id = inst.Origin
+	}
+
 	named := p.table.Declarations[id]
 	if named.Named == nil {
-		return nil, fmt.Errorf(string(id) + " is not a named typed")
+		return nil, fmt.Errorf("%s is not a named typed", id)
+	}
+
+	if len(named.Named.TypeParams) > 0 && typeArgs == nil {
+		return nil, fmt.Errorf("%s is a generic interface and must be instantiated", id)
 	}
 
 	iface := p.table.Declarations[named.Named.Underlying]
 	if iface.Interface == nil {
-		return nil, fmt.Errorf(string(id) + " is not an interface")
+		return nil, fmt.Errorf("%s is not an interface", id)
 	}
 
 	pkg := p.table.Packages[p.importTable[id]]
@@ -89,7 +298,6 @@ func (p *Project) Implement(id meta.DeclId, f func(ctx MethodContext)) (*src.Typ
 	strct := src.NewStruct(named.Named.Name + "Impl")
 	strct.SetDoc("... implements the interface " + pkg.Path + "." + named.Named.Name + "\n" + named.Named.Doc)
 	for _, methId := range iface.Interface.AllMethods {
-		fmt.Println("methodId: ", methId)
 		namedMethod := p.table.Declarations[methId]
 		if namedMethod.Named == nil {
 			panic("method '" + string(methId) + "' must refer to a named signature")
@@ -106,12 +314,12 @@ func (p *Project) Implement(id meta.DeclId, f func(ctx MethodContext)) (*src.Typ
 			SetDoc(namedMethod.Named.Doc)
 
 		for _, par := range signature.Signature.Params {
-			param := src.NewParameter(par.Name, p.TypeDecl(par.DeclId))
+			param := src.NewParameter(par.Name, p.typeDecl(par.DeclId, typeArgs))
 			method.AddParams(param)
 		}
 
 		for _, par := range signature.Signature.Results {
-			param := src.NewParameter(par.Name, p.TypeDecl(par.DeclId))
+			param := src.NewParameter(par.Name, p.typeDecl(par.DeclId, typeArgs))
 			method.AddResults(param)
 		}
 
@@ -0,0 +1,113 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"github.com/golangee/reflectplus/meta"
+)
+
+// applyScope removes all declarations, which are not reachable from the declarations of the root packages. The
+// distance of a declaration is the amount of named types of dependencies, which are passed through to reach it.
+// Named types of dependencies with a distance of depth+1 are kept as opaque declarations, so that the table
+// never refers to missing declarations. Anonymous types, aliases and methods are not counted.
+func applyScope(table *meta.Table, rootPaths []string, depth int) {
+	// the universe scope, like error, is always kept
+	roots := map[meta.PkgId]bool{}
+	for _, path := range append(rootPaths, "") {
+		if pid, ok := table.PackageByImportPath(path); ok {
+			roots[pid] = true
+		}
+	}
+
+	importTable := table.CreateImportTable()
+	dependencyType := func(id meta.DeclId) bool {
+		named := table.Declarations[id].Named
+		pid, ok := importTable[id]
+		return named != nil && !named.Func && ok && !roots[pid]
+	}
+
+	// a search in levels of the distance, because the edges have the weights 0 and 1, so that each declaration
+	// is visited with its shortest distance first
+	var current, next []meta.DeclId
+	for pid, pkg := range table.Packages {
+		if roots[pid] {
+			current = append(current, pkg.Declarations...)
+		}
+	}
+
+	distances := map[meta.DeclId]int{}
+	for distance := 0; distance <= depth+1 && len(current) > 0; distance++ {
+		for len(current) > 0 {
+			id := current[len(current)-1]
+			current = current[:len(current)-1]
+			if _, ok := distances[id]; ok {
+				continue
+			}
+
+			distances[id] = distance
+			if distance > depth {
+				continue
+			}
+
+			for _, ref := range table.Declarations[id].References() {
+				if _, ok := distances[ref]; ok {
+					continue
+				}
+
+				if dependencyType(ref) {
+					next = append(next, ref)
+				} else {
+					current = append(current, ref)
+				}
+			}
+		}
+
+		current, next = next, nil
+	}
+
+	for id, decl := range table.Declarations {
+		distance, ok := distances[id]
+		switch {
+		case !ok:
+			delete(table.Declarations, id)
+		case distance > depth:
+			// only a dependency type can be beyond the depth
+			decl.Named = &meta.Named{
+				Location:        decl.Named.Location,
+				Doc:             decl.Named.Doc,
+				Annotations:     decl.Named.Annotations,
+				Name:            decl.Named.Name,
+				BuildConstraint: decl.Named.BuildConstraint,
+				Platforms:       decl.Named.Platforms,
+				Opaque:          true,
+			}
+			table.Declarations[id] = decl
+		}
+	}
+
+	for pid, pkg := range table.Packages {
+		var decls []meta.DeclId
+		for _, id := range pkg.Declarations {
+			if _, ok := distances[id]; ok {
+				decls = append(decls, id)
+			}
+		}
+
+		pkg.Declarations = decls
+		if len(decls) == 0 && !roots[pid] {
+			delete(table.Packages, pid)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 module github.com/golangee/reflectplus/internal/test
 
-go 1.14
+go 1.24
 
 require github.com/golangee/uuid v0.0.0-20200513144043-882c55e8ee6c
+
+require github.com/google/uuid v1.1.1 // indirect
@@ -13,6 +13,7 @@ func (a MyApp) Run() error {
 }
 
 // CrazyFunc is like this
+// @Command("crazy")
 func CrazyFunc() (int, error) {
 	return 0, nil
 }
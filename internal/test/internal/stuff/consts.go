@@ -0,0 +1,30 @@
+package stuff
+
+// Status is an enum
+type Status int
+
+// Status values
+const (
+	// Unknown is the default
+	// @Default
+	Unknown Status = iota
+	Running
+	Stopped
+)
+
+// Greeting is untyped
+const Greeting = "hello"
+
+const (
+	Pi       = 3.14
+	Third    = 1.0 / 3
+	Two      = 2.0
+	Avogadro = 6.02214076e23
+	Tiny     = 1e-300
+	Huge     = 1e5000
+	Wave     = 2.5i
+	Big      = 1 << 100
+	Enabled  = true
+
+	notExported = 1
+)
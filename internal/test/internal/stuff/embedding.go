@@ -0,0 +1,62 @@
+package stuff
+
+// Entity is the common base of all domain entities
+type Entity struct {
+	// ID of the entity
+	ID   string
+	Kind string
+}
+
+func (e Entity) Identifier() string {
+	return e.ID
+}
+
+func (e *Entity) SetIdentifier(id string) {
+	e.ID = id
+}
+
+// Auditable is embedded as a pointer
+type Auditable struct {
+	CreatedBy string
+}
+
+// Customer is a composed domain entity
+type Customer struct {
+	// Entity provides the identity
+	Entity
+	*Auditable `json:"audit"`
+	SomeIface
+	Name string
+	// Kind shadows Entity.Kind
+	Kind int
+}
+
+// Identifiable is only implemented by pointers of Entity and Customer
+type Identifiable interface {
+	Identifier() string
+	SetIdentifier(id string)
+}
+
+// Identity is an alias of an interface
+type Identity = Identifiable
+
+// Labeled embeds an interface by its alias
+type Labeled interface {
+	Identity
+}
+
+// NewCustomer is a constructor by convention
+func NewCustomer(name string) (Customer, error) {
+	return Customer{Name: name}, nil
+}
+
+// MakeEntity is a constructor by annotation
+// @Constructor
+func MakeEntity(id string) *Entity {
+	return &Entity{ID: id}
+}
+
+// NewName is not a constructor
+func NewName() string {
+	return ""
+}
@@ -0,0 +1,75 @@
+package stuff
+
+// Repository is a generic repository
+// @ee.Repo("entity")
+type Repository[T any] interface {
+	// FindAll returns all entities
+	FindAll() ([]T, error)
+
+	// Save persists the entity
+	Save(entity T) error
+}
+
+// CrudRepository embeds an instantiated generic interface
+type CrudRepository[T any] interface {
+	Repository[T]
+	Delete(entity T) error
+}
+
+// MyStructRepository is an instantiation of a generic interface
+type MyStructRepository = Repository[MyStruct]
+
+// Number is a constraint with a type set
+type Number interface {
+	~int | ~int64 | float64
+}
+
+// Page is a generic struct
+type Page[T any, N Number] struct {
+	Items []T
+	Total N
+	Next  *Page[T, N]
+}
+
+// Set is a generic alias
+type Set[T comparable] = map[T]struct{}
+
+// StringSet instantiates a generic alias
+type StringSet = Set[string]
+
+// Sortable has a recursive constraint
+type Sortable[T interface{ Less(other T) bool }] []T
+
+// Sum is a generic function
+func Sum[N Number](values ...N) N {
+	var sum N
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum
+}
+
+// MyStructRepo embeds an instantiated generic interface and declares its own method
+type MyStructRepo interface {
+	Repository[MyStruct]
+	Count() int
+}
+
+// MyStructStore implements MyStructRepo
+type MyStructStore struct{}
+
+// FindAll returns nothing
+func (MyStructStore) FindAll() ([]MyStruct, error) {
+	return nil, nil
+}
+
+// Save does nothing
+func (MyStructStore) Save(entity MyStruct) error {
+	return nil
+}
+
+// Count returns zero
+func (MyStructStore) Count() int {
+	return 0
+}
@@ -0,0 +1,8 @@
+//go:build integration
+
+package stuff
+
+// IntegrationOnly is only declared with the integration build tag
+type IntegrationOnly struct {
+	Endpoint string
+}
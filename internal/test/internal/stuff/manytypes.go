@@ -39,7 +39,8 @@ func NewMyStruct() *MyStruct {
 	return nil
 }
 
-// SomeMethod0 doc
+// SomeMethod0 is implemented by MyStruct
+// @Transactional
 func (s *MyStruct) SomeMethod0() {
 
 }
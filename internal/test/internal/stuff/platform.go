@@ -0,0 +1,14 @@
+package stuff
+
+// Releaser is implemented by a Resource only on windows
+type Releaser interface {
+	Release() error
+}
+
+// Resource is declared for every platform, but is only releasable on windows
+type Resource struct {
+	Name string
+}
+
+// DefaultName is declared for every platform
+const DefaultName = "resource"
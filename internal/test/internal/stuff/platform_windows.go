@@ -0,0 +1,25 @@
+package stuff
+
+// WindowsOnly is only declared for windows by the file name
+type WindowsOnly struct {
+	Handle uintptr
+}
+
+// NewResource creates a Resource only on windows
+func NewResource() *Resource {
+	return &Resource{Name: DefaultName}
+}
+
+// Release implements Releaser only on windows
+func (r *Resource) Release() error {
+	return nil
+}
+
+// WindowsSeparator is only declared for windows
+const WindowsSeparator = `\`
+
+// WindowsHandles is only declared for windows
+var WindowsHandles []uintptr
+
+// WindowsResource is only declared for windows
+type WindowsResource = Resource
@@ -0,0 +1,16 @@
+package stuff
+
+// Tree refers to itself and to the Forest, which refers back to the Tree
+type Tree struct {
+	Children []*Tree
+	Forest   *Forest
+}
+
+// Visit refers to its own receiver type
+func (t *Tree) Visit(f func(*Tree) bool) {
+}
+
+// Forest is mutually recursive with the Tree
+type Forest struct {
+	Trees map[string]Tree
+}
@@ -0,0 +1,6 @@
+package stuff
+
+// TestOnly is only declared in a _test.go file
+type TestOnly struct {
+	Expected string
+}
@@ -0,0 +1,14 @@
+package stuff
+
+import "errors"
+
+// ErrNotFound is returned if nothing has been found
+// @Error("code":404)
+var ErrNotFound = errors.New("not found")
+
+var (
+	// DefaultStruct is used if nothing else is configured
+	DefaultStruct = MyStruct{Blub: 5}
+
+	Registry, lookup = map[string]MyFunc{}, 1
+)
@@ -40,6 +40,15 @@ const (
 	String        = "string"
 	UnsafePointer = "unsafe.Pointer"
 
+	// types for untyped values
+	UntypedBool    = "untyped bool"
+	UntypedInt     = "untyped int"
+	UntypedRune    = "untyped rune"
+	UntypedFloat   = "untyped float"
+	UntypedComplex = "untyped complex"
+	UntypedString  = "untyped string"
+	UntypedNil     = "untyped nil"
+
 	// aliases
 	Byte = Uint8
 	Rune = Int32
@@ -55,6 +64,8 @@ func (b BasicKind) IsFloat() bool {
 	case Float32:
 		fallthrough
 	case Float64:
+		fallthrough
+	case UntypedFloat:
 		return true
 	default:
 		return false
@@ -62,7 +73,7 @@ func (b BasicKind) IsFloat() bool {
 }
 
 func (b BasicKind) IsString() bool {
-	return b == String
+	return b == String || b == UntypedString
 }
 
 func (b BasicKind) IsInteger() bool {
@@ -88,6 +99,32 @@ func (b BasicKind) IsInteger() bool {
 	case Uint64:
 		fallthrough
 	case Uintptr:
+		fallthrough
+	case UntypedInt:
+		fallthrough
+	case UntypedRune:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsUntyped returns true, if the kind describes the type of an untyped constant or nil.
+func (b BasicKind) IsUntyped() bool {
+	switch b {
+	case UntypedBool:
+		fallthrough
+	case UntypedInt:
+		fallthrough
+	case UntypedRune:
+		fallthrough
+	case UntypedFloat:
+		fallthrough
+	case UntypedComplex:
+		fallthrough
+	case UntypedString:
+		fallthrough
+	case UntypedNil:
 		return true
 	default:
 		return false
@@ -24,9 +24,11 @@ import (
 	"strconv"
 )
 
-// DeclId is usually a unique hash for a declaration (not just named types).
+// DeclId is usually a unique hash for a declaration (not just named types). Optionally it is the canonical
+// Go-like notation of the declaration itself, e.g. map[string]*github.com/myproject/stuff.MyStruct.
 type DeclId string
 
+// A DeclIdBuilder hashes a sequence of values into a DeclId.
 type DeclIdBuilder struct {
 	hasher hash.Hash
 }
@@ -35,16 +37,21 @@ func NewDeclId() *DeclIdBuilder {
 	return &DeclIdBuilder{hasher: sha256.New()}
 }
 
+// Put appends the values. Each value is prefixed by its length, so that e.g. ("ab","c") and ("a","bc") result in
+// different ids.
 func (b *DeclIdBuilder) Put(values ...interface{}) *DeclIdBuilder {
 	for _, v := range values {
+		var str string
 		switch t := v.(type) {
 		case string:
-			b.hasher.Write([]byte(t))
+			str = t
 		case int:
-			b.hasher.Write([]byte(strconv.Itoa(t)))
+			str = strconv.Itoa(t)
 		default:
-			b.hasher.Write([]byte(fmt.Sprintf("%v", v)))
+			str = fmt.Sprintf("%v", v)
 		}
+
+		b.hasher.Write([]byte(strconv.Itoa(len(str)) + ":" + str))
 	}
 
 	return b
@@ -104,6 +111,14 @@ func (t *Table) PutDeclaration(q DeclId, p Type) {
 }
 
 func (t *Table) PutNamedDeclaration(importPath, pkgName string, q DeclId, p *Named) {
+	t.PutPackageDeclaration(importPath, pkgName, q, Type{
+		Named: p,
+	})
+}
+
+// PutPackageDeclaration inserts the type and registers it in the package with the given import path, which is
+// created if required.
+func (t *Table) PutPackageDeclaration(importPath, pkgName string, q DeclId, p Type) {
 	pid, ok := t.PackageByImportPath(importPath)
 	if !ok {
 		pid = PkgId(NewDeclId().Put(importPath).Finish())
@@ -118,12 +133,73 @@ func (t *Table) PutNamedDeclaration(importPath, pkgName string, q DeclId, p *Nam
 		panic("inconsistent package name:" + pkgName + " vs " + pkg.Name)
 	}
 
-	t.PutDeclaration(q, Type{
-		Named: p,
-	})
+	t.PutDeclaration(q, p)
 	pkg.Declarations = append(pkg.Declarations, q)
 }
 
+// Merge inserts all declarations and packages of the other table, which are not already contained. Existing
+// declarations are kept as they are.
+func (t *Table) Merge(other *Table) {
+	for _, id := range other.DeclIds() {
+		if !t.HasDeclaration(id) {
+			t.PutDeclaration(id, other.Declarations[id])
+		}
+	}
+
+	for _, otherPkg := range other.Packages {
+		pid, ok := t.PackageByImportPath(otherPkg.Path)
+		if !ok {
+			pid = PkgId(NewDeclId().Put(otherPkg.Path).Finish())
+			t.Packages[pid] = &Package{
+				Path: otherPkg.Path,
+				Name: otherPkg.Name,
+			}
+		}
+		pkg := t.Packages[pid]
+
+		known := map[DeclId]bool{}
+		for _, id := range pkg.Declarations {
+			known[id] = true
+		}
+
+		for _, id := range otherPkg.Declarations {
+			if !known[id] {
+				pkg.Declarations = append(pkg.Declarations, id)
+			}
+		}
+	}
+}
+
+// MergePackage inserts the declarations of the package with the given import path of the other table, which are
+// not already contained, including the underlying and declared types of named declarations. Invoke it before
+// Merge, to prefer the declarations of the table, which has been created from the source of the package, e.g.
+// because the dependencies of another table miss the docs or the field tags.
+func (t *Table) MergePackage(other *Table, importPath string) {
+	pid, ok := other.PackageByImportPath(importPath)
+	if !ok {
+		return
+	}
+
+	pkg := other.Packages[pid]
+	for _, id := range pkg.Declarations {
+		if t.HasDeclaration(id) {
+			continue
+		}
+
+		decl := other.Declarations[id]
+		t.PutPackageDeclaration(pkg.Path, pkg.Name, id, decl)
+		if decl.Named == nil {
+			continue
+		}
+
+		for _, ref := range []DeclId{decl.Named.Underlying, decl.Named.Declared} {
+			if ref != "" && !t.HasDeclaration(ref) {
+				t.PutDeclaration(ref, other.Declarations[ref])
+			}
+		}
+	}
+}
+
 // CreateImportTable creates a new table which assigns each declaration id to its containing package id.
 func (t *Table) CreateImportTable() map[DeclId]PkgId {
 	r := map[DeclId]PkgId{}
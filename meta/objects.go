@@ -14,7 +14,10 @@
 
 package meta
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 type Location string
 
@@ -22,6 +25,37 @@ func NewLocation(filename string, line, col int) Location {
 	return Location(filename + ":" + strconv.Itoa(line) + ":" + strconv.Itoa(col))
 }
 
+// Split returns the file name, the line and the column of the location. Missing or invalid numbers are 0.
+func (l Location) Split() (filename string, line, col int) {
+	filename = string(l)
+	if idx := strings.LastIndex(filename, ":"); idx >= 0 {
+		col, _ = strconv.Atoi(filename[idx+1:])
+		filename = filename[:idx]
+	}
+
+	if idx := strings.LastIndex(filename, ":"); idx >= 0 {
+		line, _ = strconv.Atoi(filename[idx+1:])
+		filename = filename[:idx]
+	}
+
+	return
+}
+
+// Less orders locations by file name first and then numerically by line and column.
+func (l Location) Less(o Location) bool {
+	f0, l0, c0 := l.Split()
+	f1, l1, c1 := o.Split()
+	if f0 != f1 {
+		return f0 < f1
+	}
+
+	if l0 != l1 {
+		return l0 < l1
+	}
+
+	return c0 < c1
+}
+
 // A PackageQualifier consists of an import path and the according package name. This is rather obscure, because
 // you can never deduce the actual package name from its path, however it allows at least elegant solutions like this:
 //  * versioned packages, e.g. github.com/myproject/myapi and github.com/myproject/myapi/v2 should both be named myapi
@@ -14,7 +14,10 @@
 
 package meta
 
-import "github.com/golangee/reflectplus/internal/tag"
+import (
+	"github.com/golangee/reflectplus/internal/tag"
+	"strings"
+)
 
 // A ChanDir specified the declared channel direction
 type ChanDir string
@@ -25,8 +28,19 @@ const (
 	RecvOnly = "RecvOnly"
 )
 
+// A ConstKind specifies the kind of value of a constant.
+type ConstKind string
+
+const (
+	ConstBool    ConstKind = "Bool"
+	ConstString  ConstKind = "String"
+	ConstInt     ConstKind = "Int"
+	ConstFloat   ConstKind = "Float"
+	ConstComplex ConstKind = "Complex"
+)
+
 // An Type is a union tuple of exact one of Basic, Array, Channel, Interface, Map
-// Pointer or Struct.
+// Pointer, Struct, Named, Signature, Const, Var, Alias, TypeParam, Union or Instance.
 type Type struct {
 	Basic     *Basic     `json:",omitempty"`
 	Array     *Array     `json:",omitempty"`
@@ -38,6 +52,12 @@ type Type struct {
 	Struct    *Struct    `json:",omitempty"`
 	Named     *Named     `json:",omitempty"`
 	Signature *Signature `json:",omitempty"`
+	Const     *Const     `json:",omitempty"`
+	Var       *Var       `json:",omitempty"`
+	Alias     *Alias     `json:",omitempty"`
+	TypeParam *TypeParam `json:",omitempty"`
+	Union     *Union     `json:",omitempty"`
+	Instance  *Instance  `json:",omitempty"`
 }
 
 // Kind returns the first non-nil union value.
@@ -78,9 +98,112 @@ func (t Type) Kind() interface{} {
 		return t.Named
 	}
 
+	if t.Const != nil {
+		return t.Const
+	}
+
+	if t.Var != nil {
+		return t.Var
+	}
+
+	if t.Alias != nil {
+		return t.Alias
+	}
+
+	if t.TypeParam != nil {
+		return t.TypeParam
+	}
+
+	if t.Union != nil {
+		return t.Union
+	}
+
+	if t.Instance != nil {
+		return t.Instance
+	}
+
 	panic("invalid type model")
 }
 
+// References returns the ids of all declarations, which are referred to by this type, in a stable order. The
+// result may contain duplicates.
+func (t Type) References() []DeclId {
+	var res []DeclId
+	params := func(list []Param) {
+		for _, p := range list {
+			res = append(res, p.DeclId)
+		}
+	}
+
+	switch {
+	case t.Named != nil:
+		n := t.Named
+		if n.Underlying != "" {
+			res = append(res, n.Underlying)
+		}
+		if n.Declared != "" {
+			res = append(res, n.Declared)
+		}
+		res = append(res, n.Methods...)
+		res = append(res, n.TypeParams...)
+		if n.Receiver != "" {
+			res = append(res, n.Receiver)
+		}
+		res = append(res, n.ValueMethodSet...)
+		res = append(res, n.PointerMethodSet...)
+		for _, impl := range n.Implements {
+			res = append(res, impl.DeclId)
+		}
+		res = append(res, n.Constructors...)
+		for _, p := range n.PromotedFields {
+			res = append(res, p.DeclId)
+		}
+		for _, p := range n.PromotedMethods {
+			res = append(res, p.DeclId)
+		}
+	case t.Array != nil:
+		res = append(res, t.Array.DeclId)
+	case t.Slice != nil:
+		res = append(res, t.Slice.DeclId)
+	case t.Channel != nil:
+		res = append(res, t.Channel.DeclId)
+	case t.Interface != nil:
+		res = append(res, t.Interface.Embeddeds...)
+		res = append(res, t.Interface.AllMethods...)
+	case t.Map != nil:
+		res = append(res, t.Map.Key, t.Map.Value)
+	case t.Pointer != nil:
+		res = append(res, t.Pointer.Base)
+	case t.Struct != nil:
+		params(t.Struct.Fields)
+	case t.Signature != nil:
+		if t.Signature.Receiver != nil {
+			res = append(res, t.Signature.Receiver.DeclId)
+		}
+		params(t.Signature.Params)
+		params(t.Signature.Results)
+		res = append(res, t.Signature.TypeParams...)
+	case t.Const != nil:
+		res = append(res, t.Const.DeclId)
+	case t.Var != nil:
+		res = append(res, t.Var.DeclId)
+	case t.Alias != nil:
+		res = append(res, t.Alias.Target)
+		res = append(res, t.Alias.TypeParams...)
+	case t.TypeParam != nil:
+		res = append(res, t.TypeParam.Constraint)
+	case t.Union != nil:
+		for _, term := range t.Union.Terms {
+			res = append(res, term.DeclId)
+		}
+	case t.Instance != nil:
+		res = append(res, t.Instance.Origin)
+		res = append(res, t.Instance.TypeArgs...)
+	}
+
+	return res
+}
+
 // A Named type is a declared type somewhere in the source. It is not a build-in, however it may be
 // also an anonymous type, where the name is just empty.
 type Named struct {
@@ -91,11 +214,128 @@ type Named struct {
 	// Name is the LHS of the declaration or empty if no such thing
 	Name string
 
-	// Underlying is the resolved RHS side of the declaration.
+	// Underlying is the resolved RHS side of the declaration. It is empty, if the declaration is Opaque.
 	Underlying DeclId
 
+	// Declared is the RHS side of the declaration as written, e.g. MyHopString for type MyHopHopString MyHopString,
+	// so that the chain of declarations can be followed down to the Underlying type. It is the Underlying type
+	// itself for a type literal, like a struct. It is empty, if the syntax is not available, e.g. for dependencies,
+	// for functions or if the declaration is Opaque.
+	Declared DeclId `json:",omitempty"`
+
 	// Methods contains the declared methods for this named type (Signature).
 	Methods []DeclId `json:",omitempty"`
+
+	// TypeParams refers to the declared TypeParam list, if this is a generic type.
+	TypeParams []DeclId `json:",omitempty"`
+
+	// Func is true, if this is a function or method declaration instead of a type declaration. The Underlying
+	// type is always a Signature.
+	Func bool `json:",omitempty"`
+
+	// Receiver refers to the named type or interface which declares this method. It is empty for functions.
+	Receiver DeclId `json:",omitempty"`
+
+	// PointerReceiver is true, if this method is declared on *T instead of T. Such a method can only be
+	// called on addressable values and is not part of the method set of T.
+	PointerReceiver bool `json:",omitempty"`
+
+	// ValueMethodSet refers to all declared and promoted methods, which can be called on a value of this
+	// type. It is empty for interfaces, which have their method set in AllMethods.
+	ValueMethodSet []DeclId `json:",omitempty"`
+
+	// PointerMethodSet refers to all declared and promoted methods, which can be called on a pointer of
+	// this type. It is always a superset of the ValueMethodSet.
+	PointerMethodSet []DeclId `json:",omitempty"`
+
+	// Implements refers to all named interfaces of the table, which are implemented by this type or by its
+	// pointer.
+	Implements []Implementation `json:",omitempty"`
+
+	// Constructors refers to the package level functions, which create this type, like NewT() (*T, error).
+	Constructors []DeclId `json:",omitempty"`
+
+	// BuildConstraint is the build constraint of the declaring file, e.g. linux && !integration. It combines the
+	// implicit constraint of the file name, like windows for x_windows.go, with the //go:build expression.
+	BuildConstraint string `json:",omitempty"`
+
+	// Platforms contains the build configurations of a multi-platform parse, in which this declaration exists.
+	Platforms []Platform `json:",omitempty"`
+
+	// PromotedFields contains all fields which are reachable through embedded fields, like a direct field.
+	// Shadowed and ambiguous selectors are not included.
+	PromotedFields []Promoted `json:",omitempty"`
+
+	// PromotedMethods contains all methods which are reachable through embedded fields, including those
+	// which require an addressable receiver.
+	PromotedMethods []Promoted `json:",omitempty"`
+
+	// Opaque is true for a declaration of a dependency, which is outside of the scope of the parser. Only
+	// its name, location and doc are known, but not its underlying type or its methods.
+	Opaque bool `json:",omitempty"`
+}
+
+// A Platform is a build configuration.
+type Platform struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string `json:",omitempty"`
+}
+
+// String returns the notation goos/goarch[/tag,tag].
+func (p Platform) String() string {
+	s := p.GOOS + "/" + p.GOARCH
+	if len(p.Tags) > 0 {
+		s += "/" + strings.Join(p.Tags, ",")
+	}
+
+	return s
+}
+
+// An Implementation relates a named type with a named interface.
+type Implementation struct {
+	// DeclId of the interface or of the implementing type, depending on the perspective.
+	DeclId DeclId
+
+	// Pointer is true, if only the pointer type implements the interface, because at least one method has a
+	// pointer receiver.
+	Pointer bool `json:",omitempty"`
+}
+
+// A Promoted field or method is declared by an embedded field but can be selected like a direct member.
+type Promoted struct {
+	// Name of the field or method.
+	Name string
+
+	// Path contains the names of the embedded fields, which are traversed to reach the member, e.g. Base.Entity
+	// for x.Base.Entity.ID.
+	Path []string
+
+	// DeclId refers to the type of the field or to the named method declaration.
+	DeclId DeclId
+}
+
+// An Alias is a declared alternative name for another type, like type MyAlias = MyString. Both denote the
+// identical type, however the alias name is kept, so that it can be preserved in generated code or documentation.
+type Alias struct {
+	Location    Location
+	Doc         string
+	Annotations []Annotation `json:",omitempty"`
+
+	// Name is the LHS of the declaration
+	Name string
+
+	// Target is the declared RHS of the declaration, which may be another alias.
+	Target DeclId
+
+	// TypeParams refers to the declared TypeParam list, if this is a generic alias.
+	TypeParams []DeclId `json:",omitempty"`
+
+	// BuildConstraint is the build constraint of the declaring file, see Named.BuildConstraint.
+	BuildConstraint string `json:",omitempty"`
+
+	// Platforms contains the build configurations of a multi-platform parse, in which this declaration exists.
+	Platforms []Platform `json:",omitempty"`
 }
 
 // A Basic type represents a build-in type
@@ -118,7 +358,7 @@ type Channel struct {
 
 // An Interface has a set of signatures and embedded types.
 type Interface struct {
-	// Embeddeds refers only to TypeIds of other embedded Interfaces.
+	// Embeddeds refers to TypeIds of other embedded Interfaces or of type set Unions in constraint interfaces.
 	Embeddeds []DeclId
 
 	// AllMethods refers only to TypeIds of Signatures included by all declared methods, also
@@ -150,6 +390,9 @@ type Signature struct {
 
 	// Variadic indicates if the last parameter is a ...T declaration.
 	Variadic bool `json:",omitempty"`
+
+	// TypeParams refers to the declared TypeParam list, if this is a generic function.
+	TypeParams []DeclId `json:",omitempty"`
 }
 
 // A Param is not a type but declares a tuple of name and type.
@@ -165,6 +408,9 @@ type Param struct {
 	// The type of the parameter
 	DeclId DeclId
 
+	// Embedded is true for an embedded struct field, whose Name is the name of the embedded type.
+	Embedded bool `json:",omitempty"`
+
 	// Tag is the raw string literal. The parsed literal is in Tags
 	Tag string `json:",omitempty"`
 
@@ -184,3 +430,93 @@ type Slice struct {
 type Struct struct {
 	Fields []Param `json:",omitempty"`
 }
+
+// A Const is a declared constant at package level. Its value is always known at compile time.
+type Const struct {
+	Location    Location
+	Doc         string
+	Annotations []Annotation `json:",omitempty"`
+
+	// Name is the LHS of the declaration
+	Name string
+
+	// DeclId refers to the type of the constant, which is either a named type or a basic type. Untyped
+	// constants refer to the according untyped basic kind, e.g. UntypedInt.
+	DeclId DeclId
+
+	// Kind of the Value
+	Kind ConstKind
+
+	// Value is the exact value in Go notation. Strings are quoted, floats are float literals (e.g. 3.14) or a
+	// float division, if they have no finite decimal representation (e.g. 1.0/3), and complex numbers are given
+	// as complex(re, im).
+	Value string
+
+	// Block is the location of the const declaration, which groups constants together, like an iota
+	// enumeration.
+	Block Location
+
+	// Iota is the index of the declaring spec within the Block.
+	Iota int
+
+	// BuildConstraint is the build constraint of the declaring file, see Named.BuildConstraint.
+	BuildConstraint string `json:",omitempty"`
+
+	// Platforms contains the build configurations of a multi-platform parse, in which this declaration exists.
+	Platforms []Platform `json:",omitempty"`
+}
+
+// A Var is a declared variable at package level, e.g. a sentinel error or a default configuration.
+type Var struct {
+	Location    Location
+	Doc         string
+	Annotations []Annotation `json:",omitempty"`
+
+	// Name is the LHS of the declaration
+	Name string
+
+	// DeclId refers to the type of the variable.
+	DeclId DeclId
+
+	// BuildConstraint is the build constraint of the declaring file, see Named.BuildConstraint.
+	BuildConstraint string `json:",omitempty"`
+
+	// Platforms contains the build configurations of a multi-platform parse, in which this declaration exists.
+	Platforms []Platform `json:",omitempty"`
+}
+
+// A TypeParam is a declared type parameter of a generic type or function, like T in Repository[T any]. Each
+// usage of the parameter within the generic declaration refers to it.
+type TypeParam struct {
+	// Name of the type parameter, e.g. T
+	Name string
+
+	// Index of the type parameter within its declaring list.
+	Index int
+
+	// Constraint refers to the interface which constrains the type parameter.
+	Constraint DeclId
+}
+
+// A Union is a type set, which is only allowed in constraint interfaces, like ~int | float64.
+type Union struct {
+	Terms []Term
+}
+
+// A Term is a part of a Union.
+type Term struct {
+	// Tilde is true for ~T, which includes all types with the underlying type T.
+	Tilde bool `json:",omitempty"`
+
+	// DeclId of the type T.
+	DeclId DeclId
+}
+
+// An Instance is a generic type which has been instantiated with type arguments, like Repository[MyStruct].
+type Instance struct {
+	// Origin refers to the generic declaration, which is either a Named type or an Alias.
+	Origin DeclId
+
+	// TypeArgs refer to the type arguments in the order of the declared type parameters of the origin.
+	TypeArgs []DeclId
+}
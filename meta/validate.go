@@ -0,0 +1,126 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A ValidationError describes an inconsistency of a Table.
+type ValidationError struct {
+	// DeclId of the invalid declaration.
+	DeclId DeclId
+
+	// Ref is the missing declaration, which is referred to by DeclId, or empty.
+	Ref DeclId `json:",omitempty"`
+
+	// PkgId is the package, which lists the missing declaration DeclId, or empty.
+	PkgId PkgId `json:",omitempty"`
+
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	switch {
+	case e.Ref != "":
+		return string(e.DeclId) + ": " + e.Reason + ": " + string(e.Ref)
+	case e.PkgId != "":
+		return string(e.PkgId) + ": " + e.Reason + ": " + string(e.DeclId)
+	default:
+		return string(e.DeclId) + ": " + e.Reason
+	}
+}
+
+// ValidationErrors contains all inconsistencies of a Table.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString(strconv.Itoa(len(e)))
+	sb.WriteString(" invalid declarations:")
+	for _, err := range e {
+		sb.WriteString("\n")
+		sb.WriteString(err.Error())
+	}
+
+	return sb.String()
+}
+
+// Validate checks that each declaration is exactly one kind of type and that each DeclId, which is referred to
+// by a declaration or a package, resolves to a declaration of the table. The result is either nil or
+// ValidationErrors in a stable order.
+func (t *Table) Validate() error {
+	var res ValidationErrors
+	for _, id := range t.DeclIds() {
+		decl := t.Declarations[id]
+		switch decl.kinds() {
+		case 0:
+			res = append(res, ValidationError{DeclId: id, Reason: "empty type"})
+			continue
+		case 1:
+		default:
+			res = append(res, ValidationError{DeclId: id, Reason: "ambiguous type"})
+			continue
+		}
+
+		for _, ref := range decl.References() {
+			if !t.HasDeclaration(ref) {
+				res = append(res, ValidationError{DeclId: id, Ref: ref, Reason: "missing reference"})
+			}
+		}
+	}
+
+	pkgIds := make([]PkgId, 0, len(t.Packages))
+	for pid := range t.Packages {
+		pkgIds = append(pkgIds, pid)
+	}
+	sort.Slice(pkgIds, func(i, j int) bool { return pkgIds[i] < pkgIds[j] })
+
+	for _, pid := range pkgIds {
+		for _, id := range t.Packages[pid].Declarations {
+			if !t.HasDeclaration(id) {
+				res = append(res, ValidationError{DeclId: id, PkgId: pid, Reason: "missing package declaration"})
+			}
+		}
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+
+	return res
+}
+
+// kinds returns the amount of non-nil union values, which must be exactly one.
+func (t Type) kinds() int {
+	n := 0
+	for _, set := range []bool{
+		t.Basic != nil, t.Array != nil, t.Channel != nil, t.Interface != nil, t.Map != nil, t.Pointer != nil,
+		t.Slice != nil, t.Struct != nil, t.Named != nil, t.Signature != nil, t.Const != nil, t.Var != nil,
+		t.Alias != nil, t.TypeParam != nil, t.Union != nil, t.Instance != nil,
+	} {
+		if set {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTable_Validate(t *testing.T) {
+	table := NewTable()
+	table.PutDeclaration("empty", Type{})
+	table.PutDeclaration("ambiguous", Type{Basic: &Basic{Kind: Int}, Pointer: &Pointer{Base: "empty"}})
+	table.PutPackageDeclaration("stuff", "stuff", "pointer", Type{Pointer: &Pointer{Base: "missing"}})
+	table.Packages[PkgId("stuffId")] = &Package{Path: "other", Name: "other", Declarations: []DeclId{"gone"}}
+
+	var errs ValidationErrors
+	if !errors.As(table.Validate(), &errs) {
+		t.Fatal("expected validation errors")
+	}
+
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+
+	expected := []string{
+		"ambiguous: ambiguous type",
+		"empty: empty type",
+		"pointer: missing reference: missing",
+		"stuffId: missing package declaration: gone",
+	}
+
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+}
@@ -16,6 +16,7 @@
 package mod
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os/exec"
@@ -72,9 +73,19 @@ type ModuleError struct {
 
 // List invokes "go list -json -m all" in the given directory and returns a flat list of all used modules.
 func List(dir string) (Modules, error) {
-	cmd := exec.Command("go", "list", "-json", "-m", "all")
+	return ListContext(context.Background(), dir)
+}
+
+// ListContext works like List, but kills the go process, as soon as the context is done. In that case, the error
+// of the context is returned.
+func ListContext(ctx context.Context, dir string) (Modules, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-json", "-m", "all")
 	cmd.Dir = dir
 	res, err := cmd.CombinedOutput()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("unable to 'go mod list -json -m all': %w", err)
 	}
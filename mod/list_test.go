@@ -15,6 +15,8 @@
 package mod
 
 import (
+	"context"
+	"errors"
 	"testing"
 )
 
@@ -28,3 +30,12 @@ func TestList(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestListContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ListContext(ctx, "."); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation but got %v", err)
+	}
+}
@@ -16,26 +16,50 @@
 package reflectplus
 
 import (
+	"context"
 	"github.com/golangee/reflectplus/golang"
 	"github.com/golangee/reflectplus/mod"
+	"go/build"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
-// Parse loads initiates the tooling in the given folders and loads and parses all given paths from the pattern
-// including all related dependencies, including declaration from the standard library.
+// Parse loads initiates the tooling in the given folders and loads and parses all given paths from the pattern.
+// Declarations of dependencies, including the standard library, are only included if referenced.
 func Parse(opts golang.Options) (*golang.Project, error) {
-	return golang.NewProject(opts)
+	return ParseContext(context.Background(), opts)
+}
+
+// ParseContext works like Parse, but stops as soon as the context is done, e.g. due to a timeout. In that case,
+// the error of the context is returned.
+func ParseContext(ctx context.Context, opts golang.Options) (*golang.Project, error) {
+	return golang.NewProjectContext(ctx, opts)
 }
 
 // ParseModule can be invoked from any subdirectory within a valid go module and parses the module including all
 // of its dependencies.
 func ParseModule() (*golang.Project, error) {
+	return ParseModuleWith(golang.Options{})
+}
+
+// ParseModuleWith works like ParseModule but applies the given options. Dir and Patterns are always replaced
+// by the detected module.
+func ParseModuleWith(opts golang.Options) (*golang.Project, error) {
+	return ParseModuleContext(context.Background(), opts)
+}
+
+// ParseModuleContext works like ParseModuleWith, but stops as soon as the context is done, including the listing
+// of the modules.
+func ParseModuleContext(ctx context.Context, opts golang.Options) (*golang.Project, error) {
 	dir, err := os.Getwd()
 	if err != nil {
 		return nil, err
 	}
 
-	modules, err := mod.List(dir)
+	modules, err := mod.ListContext(ctx, dir)
 	if err != nil {
 		return nil, err
 	}
@@ -49,8 +73,59 @@ func ParseModule() (*golang.Project, error) {
 		patterns = append(patterns, module.Path)
 	}
 
-	return Parse(golang.Options{
-		Dir:      rootDir,
-		Patterns: patterns,
-	})
+	opts.Dir = rootDir
+	opts.Patterns = patterns
+
+	return ParseContext(ctx, opts)
+}
+
+// ParseSource parses go files, which only exist in memory, e.g. freshly generated code. The file names are slash
+// separated and relative to a virtual module root, like stuff/stuff.go. If no go.mod is given, the module is
+// named "source". See also ParseSourceWith.
+func ParseSource(files map[string][]byte) (*golang.Project, error) {
+	return ParseSourceWith(golang.Options{}, files)
+}
+
+// ParseSourceWith works like ParseSource but applies the given options. Dir, Patterns and Overlay are always
+// replaced. The locations of the declarations refer to a temporary directory, which is removed afterwards.
+func ParseSourceWith(opts golang.Options, files map[string][]byte) (*golang.Project, error) {
+	return ParseSourceContext(context.Background(), opts, files)
+}
+
+// ParseSourceContext works like ParseSourceWith, but stops as soon as the context is done.
+func ParseSourceContext(ctx context.Context, opts golang.Options, files map[string][]byte) (*golang.Project, error) {
+	// the build system requires an existing working directory, all files are only in the overlay
+	dir, err := os.MkdirTemp("", "reflectplus-source")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	overlay := map[string][]byte{}
+	pkgDirs := map[string]bool{}
+	for name, content := range files {
+		overlay[filepath.Join(dir, filepath.FromSlash(name))] = content
+		if strings.HasSuffix(name, ".go") {
+			pkgDirs["./"+path.Dir(name)] = true
+		}
+	}
+
+	if _, ok := files["go.mod"]; !ok {
+		tags := build.Default.ReleaseTags
+		goVersion := strings.TrimPrefix(tags[len(tags)-1], "go")
+		overlay[filepath.Join(dir, "go.mod")] = []byte("module source\n\ngo " + goVersion + "\n")
+	}
+
+	var patterns []string
+	for pkgDir := range pkgDirs {
+		patterns = append(patterns, pkgDir)
+	}
+	sort.Strings(patterns)
+
+	opts.Dir = dir
+	opts.Patterns = patterns
+	opts.Overlay = overlay
+
+	return ParseContext(ctx, opts)
 }
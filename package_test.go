@@ -0,0 +1,45 @@
+// Copyright 2020 Torben Schinke
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectplus
+
+import (
+	"github.com/golangee/reflectplus/golang"
+	"github.com/golangee/reflectplus/meta"
+	"strings"
+	"testing"
+)
+
+func TestParseSource(t *testing.T) {
+	prj, err := ParseSourceWith(golang.Options{CanonicalIds: true}, map[string][]byte{
+		"stuff/stuff.go": []byte("package stuff\n\n// Generated is not on disk\ntype Generated struct {\n\tName string\n}\n"),
+		"app/app.go":     []byte("package app\n\nimport \"source/stuff\"\n\n// NewGenerated creates it\nfunc NewGenerated() *stuff.Generated {\n\treturn nil\n}\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var funcs []meta.DeclId
+	prj.ForEachFunc(func(pkg *meta.Package, id meta.DeclId, named *meta.Named, sig *meta.Signature) {
+		funcs = append(funcs, id)
+	})
+
+	if len(funcs) != 1 || funcs[0] != "source/app.NewGenerated" {
+		t.Fatalf("unexpected functions %v", funcs)
+	}
+
+	if !strings.Contains(prj.String(), "Generated is not on disk") {
+		t.Fatal("expected the in-memory type declaration")
+	}
+}